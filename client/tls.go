@@ -0,0 +1,51 @@
+// Package client sends syslog messages to a remote collector.
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/deadspacewii/psyslog/transport"
+	"net"
+	"sync"
+	"time"
+)
+
+// DIALTIMEOUT bounds connecting and the TLS handshake.
+const DIALTIMEOUT = 30 * time.Second
+
+// TLSClient sends octet counted frames over TLS as described in
+// https://tools.ietf.org/html/rfc5425
+type TLSClient struct {
+	mu   sync.Mutex
+	conn *tls.Conn
+}
+
+// DialTLS connects to addr and completes the handshake. Mutual TLS and
+// pinning are set up through config, see transport.PinClient.
+func DialTLS(addr string, config *tls.Config) (*TLSClient, error) {
+	dialer := &net.Dialer{Timeout: DIALTIMEOUT}
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TLSClient{conn: conn}, nil
+}
+
+// Send writes msg as one frame, it is safe for concurrent use.
+func (c *TLSClient) Send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return transport.WriteFrame(c.conn, transport.FramingOctetCounting, msg)
+}
+
+// PeerCertificate returns the certificate presented by the server.
+func (c *TLSClient) PeerCertificate() *x509.Certificate {
+	return transport.PeerCertificate(c.conn.ConnectionState())
+}
+
+func (c *TLSClient) Close() error {
+	return c.conn.Close()
+}
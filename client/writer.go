@@ -0,0 +1,272 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"github.com/deadspacewii/psyslog/transport"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedScheme = errors.New("Unsupported transport scheme")
+	ErrWriterClosed      = errors.New("Writer is closed")
+)
+
+const (
+	DEFAULTRETRIES    = 3
+	DEFAULTMINBACKOFF = 100 * time.Millisecond
+	DEFAULTMAXBACKOFF = 5 * time.Second
+)
+
+// Builder is implemented by rfc3164.Builder and rfc5424.Builder.
+type Builder interface {
+	Build() error
+	String() string
+}
+
+// Writer sends every message to the collector named by its URL:
+//
+//	udp://host:port    one message per datagram
+//	tcp://host:port    octet counted frames, see WithFraming
+//	tls://host:port    octet counted frames over TLS, see WithTLSConfig
+//	unix:///dev/log    datagram socket, NUL terminated stream as fallback
+//
+// The connection is opened on first use and re-opened with an exponential
+// backoff after a failed write. Writer is safe for concurrent use, messages
+// go out in the order of the writes: one waiting to be retried holds back
+// the writes after it.
+type Writer struct {
+	scheme     string
+	addr       string
+	framing    transport.Framing
+	tlsConfig  *tls.Config
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// sendMu orders the messages, mu guards the connection and is released
+	// during a backoff so that Close can interrupt it
+	sendMu      sync.Mutex
+	mu          sync.Mutex
+	conn        net.Conn
+	stream      bool
+	connFraming transport.Framing
+	closed      bool
+	done        chan struct{}
+}
+
+func NewWriter(rawURL string) (*Writer, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		scheme:     u.Scheme,
+		addr:       u.Host,
+		framing:    transport.FramingOctetCounting,
+		retries:    DEFAULTRETRIES,
+		minBackoff: DEFAULTMINBACKOFF,
+		maxBackoff: DEFAULTMAXBACKOFF,
+		done:       make(chan struct{}),
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp", "tls":
+	case "unix":
+		w.addr = u.Path
+	default:
+		return nil, ErrUnsupportedScheme
+	}
+
+	return w, nil
+}
+
+func (w *Writer) WithTLSConfig(config *tls.Config) *Writer {
+	w.tlsConfig = config
+	return w
+}
+
+// WithFraming sets the framing of tcp:// streams, tls:// always uses octet
+// counting as required by RFC 5425.
+func (w *Writer) WithFraming(framing transport.Framing) *Writer {
+	w.framing = framing
+	return w
+}
+
+// WithBackoff sets how many times a failed write is retried on a new
+// connection and the bounds of the delay doubling between attempts.
+func (w *Writer) WithBackoff(retries int, min, max time.Duration) *Writer {
+	w.retries = retries
+	w.minBackoff = min
+	w.maxBackoff = max
+	return w
+}
+
+// Write sends p as a single message, trailing line endings added by
+// loggers are dropped. It implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.send(bytes.TrimRight(p, "\r\n")); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// WriteBuilder builds b and sends the result.
+func (w *Writer) WriteBuilder(b Builder) error {
+	if err := b.Build(); err != nil {
+		return err
+	}
+
+	return w.send([]byte(b.String()))
+}
+
+// WriteMessage sends m formatted as RFC 5424.
+func (w *Writer) WriteMessage(m *common.Message) error {
+	return w.WriteBuilder(rfc5424.NewBuilderFromMessage(m))
+}
+
+// Close closes the connection, a write waiting to be retried returns
+// ErrWriterClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.done)
+	}
+
+	return w.closeConn()
+}
+
+func (w *Writer) send(msg []byte) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	backoff := w.minBackoff
+
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		if err = w.write(msg); err == nil {
+			return nil
+		}
+
+		w.closeConn()
+
+		if attempt >= w.retries {
+			return err
+		}
+
+		if !w.wait(backoff) {
+			return ErrWriterClosed
+		}
+
+		backoff *= 2
+		if backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+// wait sleeps for d with mu released so that Close goes on, it reports
+// false when the Writer was closed meanwhile. It is called with mu held.
+func (w *Writer) wait(d time.Duration) bool {
+	w.mu.Unlock()
+
+	timer := time.NewTimer(d)
+
+	select {
+	case <-timer.C:
+	case <-w.done:
+		timer.Stop()
+	}
+
+	w.mu.Lock()
+
+	return !w.closed
+}
+
+func (w *Writer) write(msg []byte) error {
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case !w.stream:
+		_, err := w.conn.Write(msg)
+		return err
+	case w.scheme == "unix":
+		// glibc syslog(3) terminates messages on stream sockets with NUL
+		_, err := w.conn.Write(append(msg[:len(msg):len(msg)], 0))
+		return err
+	}
+
+	return transport.WriteFrame(w.conn, w.connFraming, msg)
+}
+
+func (w *Writer) dial() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	dialer := &net.Dialer{Timeout: DIALTIMEOUT}
+	stream := true
+	framing := w.framing
+
+	switch w.scheme {
+	case "udp":
+		conn, err = dialer.Dial("udp", w.addr)
+		stream = false
+	case "tcp":
+		conn, err = dialer.Dial("tcp", w.addr)
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.addr, w.tlsConfig)
+		framing = transport.FramingOctetCounting
+	case "unix":
+		conn, err = dialer.Dial("unixgram", w.addr)
+		stream = false
+
+		if err != nil {
+			conn, err = dialer.Dial("unix", w.addr)
+			stream = true
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	w.conn = conn
+	w.stream = stream
+	w.connFraming = framing
+	return nil
+}
+
+func (w *Writer) closeConn() error {
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,348 @@
+package client_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"github.com/deadspacewii/psyslog/client"
+	"github.com/deadspacewii/psyslog/transport"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const writerMessage = "<34>Oct 11 22:14:15 mymachine su: 'su root' failed"
+
+// readStream returns the first size bytes received on the first connection
+// accepted on l.
+func readStream(l net.Listener, size int) <-chan string {
+	frames := make(chan string, 16)
+
+	go func() {
+		defer close(frames)
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		buff := make([]byte, size)
+		if _, err := io.ReadFull(conn, buff); err == nil {
+			frames <- string(buff)
+		}
+	}()
+
+	return frames
+}
+
+// selfSigned returns a certificate for 127.0.0.1 and a pool trusting it.
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "collector"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestWriterStream(t *testing.T) {
+	cert, pool := selfSigned(t)
+
+	tests := []struct {
+		name   string
+		listen func() (net.Listener, error)
+		url    func(l net.Listener) string
+		setup  func(w *client.Writer)
+		want   string
+	}{
+		{
+			name:   "tcp octet counting",
+			listen: func() (net.Listener, error) { return net.Listen("tcp", "127.0.0.1:0") },
+			url:    func(l net.Listener) string { return "tcp://" + l.Addr().String() },
+			setup:  func(w *client.Writer) {},
+			want:   strconv.Itoa(len(writerMessage)) + " " + writerMessage,
+		},
+		{
+			name:   "tcp non-transparent",
+			listen: func() (net.Listener, error) { return net.Listen("tcp", "127.0.0.1:0") },
+			url:    func(l net.Listener) string { return "tcp://" + l.Addr().String() },
+			setup:  func(w *client.Writer) { w.WithFraming(transport.FramingNonTransparent) },
+			want:   writerMessage + "\n",
+		},
+		{
+			name: "tls",
+			listen: func() (net.Listener, error) {
+				return tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+			},
+			url: func(l net.Listener) string { return "tls://" + l.Addr().String() },
+			// RFC 5425 requires octet counting whatever the framing
+			setup: func(w *client.Writer) {
+				w.WithTLSConfig(&tls.Config{RootCAs: pool}).WithFraming(transport.FramingNonTransparent)
+			},
+			want: strconv.Itoa(len(writerMessage)) + " " + writerMessage,
+		},
+		{
+			name: "unix stream",
+			listen: func() (net.Listener, error) {
+				return net.Listen("unix", filepath.Join(t.TempDir(), "log"))
+			},
+			url:   func(l net.Listener) string { return "unix://" + l.Addr().String() },
+			setup: func(w *client.Writer) {},
+			want:  writerMessage + "\x00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := tt.listen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			frames := readStream(l, 2*len(tt.want))
+
+			w, err := client.NewWriter(tt.url(l))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			tt.setup(w)
+
+			// the line ending added by loggers is dropped
+			for _, msg := range []string{writerMessage + "\n", writerMessage} {
+				if n, err := w.Write([]byte(msg)); err != nil || n != len(msg) {
+					t.Fatalf("got %d, %v, want %d", n, err, len(msg))
+				}
+			}
+
+			select {
+			case got := <-frames:
+				if want := tt.want + tt.want; got != want {
+					t.Errorf("got %q, want %q", got, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("nothing received")
+			}
+		})
+	}
+}
+
+func TestWriterDatagram(t *testing.T) {
+	tests := []struct {
+		name   string
+		listen func() (net.PacketConn, error)
+		url    func(conn net.PacketConn) string
+	}{
+		{
+			name:   "udp",
+			listen: func() (net.PacketConn, error) { return net.ListenPacket("udp", "127.0.0.1:0") },
+			url:    func(conn net.PacketConn) string { return "udp://" + conn.LocalAddr().String() },
+		},
+		{
+			name: "unix datagram",
+			listen: func() (net.PacketConn, error) {
+				return net.ListenPacket("unixgram", filepath.Join(t.TempDir(), "log"))
+			},
+			url: func(conn net.PacketConn) string { return "unix://" + conn.LocalAddr().String() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tt.listen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			w, err := client.NewWriter(tt.url(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			for _, msg := range []string{writerMessage + "\r\n", writerMessage} {
+				if _, err := w.Write([]byte(msg)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			buff := make([]byte, 1024)
+
+			// one message per datagram, without framing
+			for i := 0; i < 2; i++ {
+				n, _, err := conn.ReadFrom(buff)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := string(buff[:n]); got != writerMessage {
+					t.Errorf("got %q, want %q", got, writerMessage)
+				}
+			}
+		})
+	}
+}
+
+// a write made while another one waits to be retried goes out after it
+func TestWriterOrder(t *testing.T) {
+	// the address of a collector which is down
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	w, err := client.NewWriter("tcp://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.WithBackoff(5, 300*time.Millisecond, 300*time.Millisecond).
+		WithFraming(transport.FramingNonTransparent)
+
+	sent := make(chan error, 2)
+	go func() {
+		_, err := w.Write([]byte("<34>first"))
+		sent <- err
+	}()
+
+	// the first write is waiting for its retry when the collector is back
+	time.Sleep(50 * time.Millisecond)
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("can not listen again on %s: %v", addr, err)
+	}
+	defer l.Close()
+
+	go func() {
+		_, err := w.Write([]byte("<34>second"))
+		sent <- err
+	}()
+
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		scanner := bufio.NewScanner(conn)
+
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-sent:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Write still blocked")
+		}
+	}
+
+	for _, want := range []string{"<34>first", "<34>second"} {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q not received", want)
+		}
+	}
+}
+
+func TestWriterCloseInterruptsBackoff(t *testing.T) {
+	// a port nobody listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	w, err := client.NewWriter("tcp://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.WithBackoff(5, time.Minute, time.Minute)
+
+	sent := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte("<34>Oct 11 22:14:15 mymachine su: x"))
+		sent <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- w.Close()
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked by the backoff")
+	}
+
+	select {
+	case err := <-sent:
+		if !errors.Is(err, client.ErrWriterClosed) {
+			t.Fatalf("got %v, want %v", err, client.ErrWriterClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write still sleeping after Close")
+	}
+}
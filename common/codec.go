@@ -0,0 +1,297 @@
+package common
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TAGNAME is the struct tag read by the structured data codecs, it has the
+// form `syslog:"SD-ID,PARAM-NAME[,omitempty]"`.
+const TAGNAME = "syslog"
+
+var (
+	ErrCodecNotPointer     = errors.New("Decode target must be a non-nil pointer to struct")
+	ErrCodecNotStruct      = errors.New("Encode source must be a struct or a pointer to struct")
+	ErrCodecUnsupported    = errors.New("Unsupported field type")
+	ErrCodecMultipleValues = errors.New("Multiple values for a non slice field")
+)
+
+// FieldTag is the parsed form of a `syslog` struct tag.
+type FieldTag struct {
+	ID        string
+	Name      string
+	OmitEmpty bool
+}
+
+// Field is an exported struct field carrying a `syslog` tag.
+type Field struct {
+	Index []int
+	Type  reflect.Type
+	Tag   FieldTag
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// ParseFieldTag parses a tag value, a single component is taken as the
+// PARAM-NAME. The tag "-" and the empty tag are reported as not found.
+func ParseFieldTag(tag string) (FieldTag, bool) {
+	var ft FieldTag
+
+	if tag == "" || tag == "-" {
+		return ft, false
+	}
+
+	parts := strings.Split(tag, ",")
+
+	if len(parts) == 1 {
+		ft.Name = parts[0]
+		return ft, true
+	}
+
+	ft.ID = parts[0]
+	ft.Name = parts[1]
+
+	for _, opt := range parts[2:] {
+		if opt == "omitempty" {
+			ft.OmitEmpty = true
+		}
+	}
+
+	return ft, ft.Name != ""
+}
+
+// StructFields returns the tagged fields of t, fields of embedded structs
+// are promoted the same way encoding/json does.
+func StructFields(t reflect.Type) []Field {
+	var fields []Field
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag, tagged := ParseFieldTag(sf.Tag.Get(TAGNAME))
+
+		if sf.Anonymous && !tagged && sf.Type.Kind() == reflect.Struct {
+			for _, f := range StructFields(sf.Type) {
+				f.Index = append([]int{i}, f.Index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+
+		if !sf.IsExported() || !tagged {
+			continue
+		}
+
+		fields = append(fields, Field{
+			Index: []int{i},
+			Type:  sf.Type,
+			Tag:   tag,
+		})
+	}
+
+	return fields
+}
+
+// HasTaggedFields reports whether t is a struct, or a pointer to one, with
+// at least one `syslog` tagged field.
+func HasTaggedFields(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && len(StructFields(t)) > 0
+}
+
+// StructValue dereferences v down to the struct it points to.
+func StructValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return rv, ErrCodecNotStruct
+		}
+
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return rv, ErrCodecNotStruct
+	}
+
+	return rv, nil
+}
+
+// FormatValue is the inverse of SetValue, slices yield one string per
+// element. Empty values are skipped when omitEmpty is set.
+func FormatValue(v reflect.Value, omitEmpty bool) ([]string, error) {
+	if omitEmpty && v.IsZero() {
+		return nil, nil
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]string, 0, v.Len())
+
+		for i := 0; i < v.Len(); i++ {
+			s, err := formatScalar(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, s)
+		}
+
+		return values, nil
+	}
+
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+
+	s, err := formatScalar(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{s}, nil
+}
+
+func formatScalar(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+
+		return formatScalar(v.Elem())
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		b, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		// []byte
+		return string(v.Bytes()), nil
+	}
+
+	return "", ErrCodecUnsupported
+}
+
+// SetValue stores values into v, slices receive one element per value while
+// any other kind accepts exactly one value.
+func SetValue(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+
+		for i, value := range values {
+			if err := setScalar(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+
+		v.Set(slice)
+		return nil
+	}
+
+	if len(values) != 1 {
+		return ErrCodecMultipleValues
+	}
+
+	return setScalar(v, values[0])
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return setScalar(v.Elem(), s)
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		// []byte
+		v.SetBytes([]byte(s))
+	default:
+		return ErrCodecUnsupported
+	}
+
+	return nil
+}
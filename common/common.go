@@ -2,11 +2,11 @@ package common
 
 import (
 	"errors"
-	"strconv"
 )
 
 const (
-	NO_VERSION = -1
+	NO_VERSION    = -1
+	MAXVERSIONLEN = 3
 )
 
 type Parts map[string]interface{}
@@ -22,85 +22,107 @@ var (
 	ErrPriorityTooLong      = errors.New("Priority field too long")
 	ErrPriorityNonDigit     = errors.New("Priority field is not digit")
 	ErrPriorityBeyondNumber = errors.New("Priority must between 0 and 191")
+	ErrPriorityLeadingZero  = errors.New("Priority field has a leading zero")
 
-	ErrVersionNotFound = errors.New("Can not find version")
+	ErrVersionNotFound    = errors.New("Can not find version")
+	ErrVersionZero        = errors.New("Version can not start with 0")
+	ErrVersionTooLong     = errors.New("Version field too long")
+	ErrVersionUnsupported = errors.New("Unsupported version")
 
 	ErrTimestampUnknownFormat = errors.New("Timestamp format unknown")
 
 	ErrHostNameContainSpace = errors.New("HostName contain space")
 
 	ErrTagTooLong = errors.New("tag field too long")
+
+	ErrParserInUse = errors.New("Parser configured after its first parse")
 )
 
 type Priority struct {
 	Priority int
-	Facility int
-	Severity int
+	Facility Facility
+	Severity Severity
 }
 
 // https://tools.ietf.org/html/rfc3164#section-4.1
 func ParsePriority(buff []byte, index *int, l int) (*Priority, error) {
+	p, err := ParsePriorityValue(buff, index, l)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPriority(p), nil
+}
+
+// ParsePriorityValue is ParsePriority without allocating a Priority.
+func ParsePriorityValue(buff []byte, index *int, l int) (int, error) {
 	if l <= 0 {
-		return nil, ErrPriorityEmpty
+		return 0, ErrPriorityEmpty
 	}
 
 	if buff[*index] != '<' {
-		return nil, ErrPriorityNoStart
+		return 0, ErrPriorityNoStart
 	}
 
 	priDigit := 0
 
 	for i := 1; i < l; i++ {
 		if i >= 5 {
-			return nil, ErrPriorityTooLong
+			*index = i
+			return 0, ErrPriorityTooLong
 		}
 
 		c := buff[i]
 
 		if c == '>' {
 			if i == 1 {
-				return nil, ErrPriorityTooShort
+				*index = i
+				return 0, ErrPriorityTooShort
 			}
 
 			*index = i + 1
-			return NewPriority(priDigit), nil
+			return priDigit, nil
 		}
 
-		if IsDigit(c) {
-			v, err := strconv.Atoi(string(c))
-			if err != nil {
-				return nil, err
-			}
-
-			priDigit = (priDigit * 10) + v
-		} else {
-			return nil, ErrPriorityNonDigit
+		if !IsDigit(c) {
+			*index = i
+			return 0, ErrPriorityNonDigit
 		}
+
+		priDigit = (priDigit * 10) + int(c-'0')
 	}
 
-	return nil, ErrPriorityNoEnd
+	*index = l
+	return 0, ErrPriorityNoEnd
 }
 
+// VERSION = NONZERO-DIGIT 0*2DIGIT
 // https://tools.ietf.org/html/rfc5424#section-6.2.2
+//
+// The index is left on the VERSION when it is not well formed.
 func ParseVersion(buff []byte, index *int, l int) (int, error) {
-	if *index >= l {
+	if *index >= l || !IsDigit(buff[*index]) {
 		return NO_VERSION, ErrVersionNotFound
 	}
 
-	c := buff[*index]
-	*index++
-
-	if !IsDigit(c) {
-		return NO_VERSION, ErrVersionNotFound
+	if buff[*index] == '0' {
+		return NO_VERSION, ErrVersionZero
 	}
 
-	value, err := strconv.Atoi(string(c))
-	if err != nil {
-		*index--
-		return NO_VERSION, err
+	i := *index
+	version := 0
+
+	for ; i < l && IsDigit(buff[i]); i++ {
+		if i-*index == MAXVERSIONLEN {
+			return NO_VERSION, ErrVersionTooLong
+		}
+
+		version = version*10 + int(buff[i]-'0')
 	}
 
-	return value, nil
+	*index = i
+
+	return version, nil
 }
 
 func IsDigit(c byte) bool {
@@ -110,8 +132,8 @@ func IsDigit(c byte) bool {
 func NewPriority(p int) *Priority {
 	return &Priority{
 		Priority: p,
-		Facility: p / 8,
-		Severity: p % 8,
+		Facility: Facility(p / 8),
+		Severity: Severity(p % 8),
 	}
 }
 
@@ -121,23 +143,31 @@ func Parse2Digits(buff []byte, index *int, l int, min int, max int, err error) (
 		return 0, ErrEOL
 	}
 
-	sub := string(buff[*index : *index+digitLen])
-
-	*index += digitLen
+	d1, d2 := buff[*index], buff[*index+1]
 
-	value, e := strconv.Atoi(sub)
-	if e != nil {
-		return 0, e
+	if !IsDigit(d1) || !IsDigit(d2) {
+		return 0, err
 	}
 
+	value := int(d1-'0')*10 + int(d2-'0')
+
 	if value < min || value > max {
 		return 0, err
 	}
 
+	*index += digitLen
+
 	return value, nil
 }
 
 func ParseHostname(buff []byte, index *int, l int) (string, error) {
+	hostname, err := ScanHostname(buff, index, l)
+
+	return string(hostname), err
+}
+
+// ScanHostname is ParseHostname returning a view into buff.
+func ScanHostname(buff []byte, index *int, l int) ([]byte, error) {
 	from := *index
 	var to int
 
@@ -147,17 +177,22 @@ func ParseHostname(buff []byte, index *int, l int) (string, error) {
 		}
 	}
 
-	hostname := buff[from:to]
-
 	*index = to
 
-	return string(hostname), nil
+	return buff[from:to], nil
 }
 
 func ParseUpToLen(buff []byte, index *int, l int, maxLen int, e error) (string, error) {
+	result, err := ScanUpToLen(buff, index, l, maxLen, e)
+
+	return string(result), err
+}
+
+// ScanUpToLen is ParseUpToLen returning a view into buff.
+func ScanUpToLen(buff []byte, index *int, l int, maxLen int, e error) ([]byte, error) {
 	var to int
 	var found bool
-	var result string
+	var result []byte
 
 	max := *index + maxLen
 
@@ -169,7 +204,7 @@ func ParseUpToLen(buff []byte, index *int, l int, maxLen int, e error) (string,
 	}
 
 	if found {
-		result = string(buff[*index:to])
+		result = buff[*index:to]
 	}
 
 	*index = to
@@ -178,28 +213,36 @@ func ParseUpToLen(buff []byte, index *int, l int, maxLen int, e error) (string,
 		return result, nil
 	}
 
-	return "", e
+	return nil, e
 }
 
 func ParseUpToLenOrData(buff []byte, index *int, l int, maxLen int, e error) (string, error) {
+	result, err := ScanUpToLenOrData(buff, index, l, maxLen, e)
+
+	return string(result), err
+}
+
+// ScanUpToLenOrData is ParseUpToLenOrData returning a view into buff.
+func ScanUpToLenOrData(buff []byte, index *int, l int, maxLen int, e error) ([]byte, error) {
 	var to int
 	var found bool
-	var result string
+	var result []byte
 
 	max := *index + maxLen
 
 	for to = *index; (to < max) && (to < l); to++ {
 		if buff[to] == ' ' || buff[to] == '[' {
-			if buff[to] == ' ' {
-				to++
-			}
 			found = true
 			break
 		}
 	}
 
 	if found {
-		result = string(buff[*index:to])
+		result = buff[*index:to]
+
+		if buff[to] == ' ' {
+			to++
+		}
 	}
 
 	*index = to
@@ -208,7 +251,7 @@ func ParseUpToLenOrData(buff []byte, index *int, l int, maxLen int, e error) (st
 		return result, nil
 	}
 
-	return "", e
+	return nil, e
 }
 
 func CheckPriority(priority int) error {
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SNIPPETLEN is the number of input bytes quoted by a ParseError.
+const SNIPPETLEN = 16
+
+// ParseError locates a parse failure in the input. Err is one of the
+// sentinel errors, so errors.Is keeps working through Unwrap.
+type ParseError struct {
+	Field    string
+	Offset   int
+	Snippet  string
+	Expected string
+	Err      error
+}
+
+// NewParseError reports err at offset of buff while parsing field, the
+// snippet quotes the input from offset on.
+func NewParseError(buff []byte, offset int, field, expected string, err error) *ParseError {
+	e := &ParseError{
+		Field:    field,
+		Offset:   offset,
+		Expected: expected,
+		Err:      err,
+	}
+
+	if offset >= 0 && offset < len(buff) {
+		end := offset + SNIPPETLEN
+		if end > len(buff) {
+			end = len(buff)
+		}
+
+		e.Snippet = string(buff[offset:end])
+	}
+
+	return e
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+
+	if e.Field != "" {
+		sb.WriteString(e.Field)
+		sb.WriteString(": ")
+	}
+
+	fmt.Fprintf(&sb, "%s at offset %d", e.Err.Error(), e.Offset)
+
+	if e.Snippet != "" {
+		fmt.Fprintf(&sb, " near %q", e.Snippet)
+	} else {
+		sb.WriteString(" at end of line")
+	}
+
+	if e.Expected != "" {
+		sb.WriteString(", expected ")
+		sb.WriteString(e.Expected)
+	}
+
+	return sb.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
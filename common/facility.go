@@ -0,0 +1,211 @@
+package common
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrFacilityUnknown = errors.New("Unknown facility")
+	ErrSeverityUnknown = errors.New("Unknown severity")
+)
+
+// Facility is the facility part of PRI, see
+// https://tools.ietf.org/html/rfc5424#section-6.2.1. It is marshalled as
+// its syslog.conf keyword, or as its number when out of range as a lenient
+// parser leaves it for a PRIVAL over 191.
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityNTP
+	FacilityAudit
+	FacilityAlert
+	FacilityClock
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Severity is the severity part of PRI, see
+// https://tools.ietf.org/html/rfc5424#section-6.2.1. It is marshalled as
+// its syslog.conf keyword, or as its number when out of range.
+type Severity int
+
+const (
+	SeverityEmerg Severity = iota
+	SeverityAlert
+	SeverityCrit
+	SeverityErr
+	SeverityWarning
+	SeverityNotice
+	SeverityInfo
+	SeverityDebug
+)
+
+var facilityNames = [...]string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "audit", "alert", "clock",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severityNames = [...]string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// the deprecated and long keywords accepted on top of the names
+var (
+	facilityAliases = map[string]Facility{
+		"security": FacilityAuth,
+	}
+	severityAliases = map[string]Severity{
+		"panic":         SeverityEmerg,
+		"emergency":     SeverityEmerg,
+		"critical":      SeverityCrit,
+		"error":         SeverityErr,
+		"warn":          SeverityWarning,
+		"informational": SeverityInfo,
+	}
+)
+
+// PriorityOf returns the PRIVAL of f and s.
+func PriorityOf(f Facility, s Severity) int {
+	return int(f)*8 + int(s)
+}
+
+// ParseFacility returns the facility named s, a keyword such as "local4"
+// or its number.
+func ParseFacility(s string) (Facility, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	for i, name := range facilityNames {
+		if name == s {
+			return Facility(i), nil
+		}
+	}
+
+	if f, ok := facilityAliases[s]; ok {
+		return f, nil
+	}
+
+	if n, err := strconv.Atoi(s); err == nil && Facility(n).Valid() {
+		return Facility(n), nil
+	}
+
+	return 0, ErrFacilityUnknown
+}
+
+// ParseSeverity returns the severity named s, a keyword such as "warning"
+// or its number.
+func ParseSeverity(s string) (Severity, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	for i, name := range severityNames {
+		if name == s {
+			return Severity(i), nil
+		}
+	}
+
+	if sev, ok := severityAliases[s]; ok {
+		return sev, nil
+	}
+
+	if n, err := strconv.Atoi(s); err == nil && Severity(n).Valid() {
+		return Severity(n), nil
+	}
+
+	return 0, ErrSeverityUnknown
+}
+
+// Valid reports whether f is one of the 24 facilities.
+func (f Facility) Valid() bool {
+	return f >= 0 && int(f) < len(facilityNames)
+}
+
+func (f Facility) String() string {
+	if !f.Valid() {
+		return "facility(" + strconv.Itoa(int(f)) + ")"
+	}
+
+	return facilityNames[f]
+}
+
+func (f Facility) MarshalText() ([]byte, error) {
+	if !f.Valid() {
+		return strconv.AppendInt(nil, int64(f), 10), nil
+	}
+
+	return []byte(facilityNames[f]), nil
+}
+
+// UnmarshalText accepts what ParseFacility does and the number of an out of
+// range facility, as MarshalText writes it.
+func (f *Facility) UnmarshalText(text []byte) error {
+	v, err := ParseFacility(string(text))
+	if err != nil {
+		n, e := strconv.Atoi(string(text))
+		if e != nil || n < 0 {
+			return err
+		}
+
+		v = Facility(n)
+	}
+
+	*f = v
+	return nil
+}
+
+// Valid reports whether s is one of the 8 severities.
+func (s Severity) Valid() bool {
+	return s >= 0 && int(s) < len(severityNames)
+}
+
+func (s Severity) String() string {
+	if !s.Valid() {
+		return "severity(" + strconv.Itoa(int(s)) + ")"
+	}
+
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	if !s.Valid() {
+		return strconv.AppendInt(nil, int64(s), 10), nil
+	}
+
+	return []byte(severityNames[s]), nil
+}
+
+// UnmarshalText accepts what ParseSeverity does and the number of an out of
+// range severity, as MarshalText writes it.
+func (s *Severity) UnmarshalText(text []byte) error {
+	v, err := ParseSeverity(string(text))
+	if err != nil {
+		n, e := strconv.Atoi(string(text))
+		if e != nil || n < 0 {
+			return err
+		}
+
+		v = Severity(n)
+	}
+
+	*s = v
+	return nil
+}
@@ -0,0 +1,94 @@
+package common_test
+
+import (
+	"encoding/json"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"strings"
+	"testing"
+)
+
+type priority struct {
+	Facility common.Facility `json:"facility"`
+	Severity common.Severity `json:"severity"`
+}
+
+func TestPriorityJSON(t *testing.T) {
+	tests := []struct {
+		pri  priority
+		json string
+	}{
+		{priority{common.FacilityKern, common.SeverityEmerg}, `{"facility":"kern","severity":"emerg"}`},
+		{priority{common.FacilityLocal4, common.SeverityNotice}, `{"facility":"local4","severity":"notice"}`},
+		{priority{common.FacilityLocal7, common.SeverityDebug}, `{"facility":"local7","severity":"debug"}`},
+		{priority{common.Facility(124), common.Severity(9)}, `{"facility":"124","severity":"9"}`},
+	}
+
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.pri)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.pri, err)
+		}
+
+		if string(b) != tt.json {
+			t.Errorf("got %s, want %s", b, tt.json)
+		}
+
+		var got priority
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+
+		if got != tt.pri {
+			t.Errorf("%s: got %+v, want %+v", b, got, tt.pri)
+		}
+	}
+}
+
+func TestPriorityUnmarshalJSON(t *testing.T) {
+	var got priority
+
+	if err := json.Unmarshal([]byte(`{"facility":"security","severity":"warn"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (priority{common.FacilityAuth, common.SeverityWarning}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{`{"facility":"nope"}`, `{"facility":"-1"}`, `{"severity":"nope"}`} {
+		if err := json.Unmarshal([]byte(bad), &got); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+}
+
+func TestResultJSONPriorityOutOfRange(t *testing.T) {
+	p3164 := rfc3164.NewParser[any, any]()
+	p3164.WithMode(common.ModeLenient)
+
+	res3164, err := p3164.ParseBytes([]byte("<999>Oct 11 22:14:15 mymachine su: x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p5424 := rfc5424.NewParser[any]()
+	p5424.WithMode(common.ModeLenient)
+
+	res5424, err := p5424.ParseBytes([]byte("<999>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range []any{res3164, res5424} {
+		b, err := json.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(b), `"facility":"124"`) || !strings.Contains(string(b), `"severity":"debug"`) {
+			t.Errorf("got %s", b)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"strings"
+	"time"
+)
+
+// SDParam is a single PARAM-NAME="PARAM-VALUE" pair, Value holds the
+// unescaped PARAM-VALUE.
+type SDParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SDElement is a single [SD-ID *(SP SD-PARAM)] block of structured data.
+type SDElement struct {
+	ID     string    `json:"id"`
+	Params []SDParam `json:"params"`
+}
+
+// Get returns the value of the first param called name.
+func (e SDElement) Get(name string) (string, bool) {
+	for _, param := range e.Params {
+		if param.Name == name {
+			return param.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// String renders the element with '"', '\' and ']' escaped in PARAM-VALUEs.
+func (e SDElement) String() string {
+	var sb strings.Builder
+
+	sb.WriteByte('[')
+	sb.WriteString(e.ID)
+
+	for _, param := range e.Params {
+		sb.WriteByte(' ')
+		sb.WriteString(param.Name)
+		sb.WriteString(`="`)
+
+		for i := 0; i < len(param.Value); i++ {
+			if c := param.Value[i]; c == '"' || c == '\\' || c == ']' {
+				sb.WriteByte('\\')
+			}
+
+			sb.WriteByte(param.Value[i])
+		}
+
+		sb.WriteByte('"')
+	}
+
+	sb.WriteByte(']')
+
+	return sb.String()
+}
+
+// Message is the format independent view of a syslog message, both
+// rfc3164 and rfc5424 results convert into it. Fields a format does not
+// carry, or carries as NILVALUE, are left empty.
+type Message struct {
+	Priority         int         `json:"priority"`
+	Facility         Facility    `json:"facility"`
+	Severity         Severity    `json:"severity"`
+	PriorityInferred bool        `json:"priority_inferred"`
+	Timestamp        time.Time   `json:"timestamp"`
+	Hostname         string      `json:"hostname"`
+	AppName          string      `json:"app_name"`
+	ProcID           string      `json:"proc_id"`
+	MsgID            string      `json:"msg_id"`
+	StructuredData   []SDElement `json:"structured_data"`
+	Message          string      `json:"message"`
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"errors"
+)
+
+// DEFAULTPRIORITY is user.notice, the PRI a relay assumes for a message
+// without one, see https://tools.ietf.org/html/rfc3164#section-4.3.3.
+const DEFAULTPRIORITY = 13
+
+var (
+	ErrNotPrintUSASCII = errors.New("Field contains a char outside PRINTUSASCII")
+	ErrExtraSpace      = errors.New("Unexpected space between fields")
+)
+
+// Rules is the set of grammar rules a parser enforces, a rule which is not
+// set is handled leniently as described on each rule.
+type Rules uint
+
+const (
+	// RulePriority requires PRI with a PRIVAL of 0 to 191, a missing one is
+	// the default priority of the parser otherwise, DEFAULTPRIORITY unless
+	// set.
+	RulePriority Rules = 1 << iota
+	// RuleNilValue requires every header field, fields missing at the end
+	// of the line are NILVALUE otherwise.
+	RuleNilValue
+	// RuleSingleSpace requires exactly one SP between fields, runs of SP
+	// are accepted otherwise.
+	RuleSingleSpace
+	// RuleFieldLength enforces the maximum field lengths, fields of any
+	// length are accepted otherwise.
+	RuleFieldLength
+	// RulePrintUSASCII restricts header fields to %d33-126.
+	RulePrintUSASCII
+	// RuleBOM requires a MSG starting with a BOM to be valid UTF-8.
+	RuleBOM
+	// RuleVersion requires a VERSION of the supported versions, any well
+	// formed VERSION is accepted otherwise.
+	RuleVersion
+	// RuleCanonicalPriority rejects a PRIVAL with a leading zero, "<013>".
+	RuleCanonicalPriority
+)
+
+const (
+	DefaultRules = RulePriority | RuleNilValue
+	StrictRules  = RulePriority | RuleNilValue | RuleSingleSpace | RuleFieldLength | RulePrintUSASCII | RuleBOM | RuleVersion | RuleCanonicalPriority
+	LenientRules = Rules(0)
+)
+
+// CheckPriority checks the PRIVAL pri, which starts at offset 1 of buff,
+// against the rules.
+func (r Rules) CheckPriority(buff []byte, pri int) error {
+	if r.Has(RulePriority) {
+		if err := CheckPriority(pri); err != nil {
+			return err
+		}
+	}
+
+	if r.Has(RuleCanonicalPriority) && len(buff) > 2 && buff[1] == '0' && buff[2] != '>' {
+		return ErrPriorityLeadingZero
+	}
+
+	return nil
+}
+
+// Has reports whether every rule of rule is set.
+func (r Rules) Has(rule Rules) bool {
+	return r&rule == rule
+}
+
+// Mode is a preset of Rules, any other combination set with WithRules makes
+// a custom mode.
+type Mode int
+
+const (
+	ModeDefault Mode = iota
+	ModeStrict
+	ModeLenient
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeStrict:
+		return "strict"
+	case ModeLenient:
+		return "lenient"
+	}
+
+	return "default"
+}
+
+// Rules returns the rules of the preset.
+func (m Mode) Rules() Rules {
+	switch m {
+	case ModeStrict:
+		return StrictRules
+	case ModeLenient:
+		return LenientRules
+	}
+
+	return DefaultRules
+}
+
+// IsPrintUSASCII reports whether c is in %d33-126.
+func IsPrintUSASCII(c byte) bool {
+	return c >= 33 && c <= 126
+}
@@ -0,0 +1,58 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SupportedVersions is the set of VERSION values a parser or a builder
+// accepts.
+type SupportedVersions []int
+
+// DefaultSupportedVersions holds the versions defined so far, RFC 5424 only
+// defines 1.
+var DefaultSupportedVersions = SupportedVersions{1}
+
+// UnsupportedVersionError reports a well formed VERSION which is not in the
+// supported versions, it wraps ErrVersionUnsupported.
+type UnsupportedVersionError struct {
+	Version int
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return ErrVersionUnsupported.Error() + " " + strconv.Itoa(e.Version)
+}
+
+func (e *UnsupportedVersionError) Unwrap() error {
+	return ErrVersionUnsupported
+}
+
+// Has reports whether version is supported.
+func (s SupportedVersions) Has(version int) bool {
+	for _, v := range s {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check returns an *UnsupportedVersionError when version is not supported.
+func (s SupportedVersions) Check(version int) error {
+	if !s.Has(version) {
+		return &UnsupportedVersionError{Version: version}
+	}
+
+	return nil
+}
+
+// String lists the versions the way a ParseError expects them, "1 or 2".
+func (s SupportedVersions) String() string {
+	versions := make([]string, len(s))
+	for i, v := range s {
+		versions[i] = strconv.Itoa(v)
+	}
+
+	return strings.Join(versions, " or ")
+}
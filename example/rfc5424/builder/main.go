@@ -12,7 +12,12 @@ func main() {
 	builder.SetVersion(1)
 	builder.SetTimestamp("2003-08-24T05:14:15.000003-07:00")
 	builder.SetHostName("test.com")
-	builder.SetStructuredData("test value for RFC5424")
+	builder.AddSDElement("exampleSDID@32473",
+		rfc5424.SDParam{Name: "iut", Value: "3"},
+		rfc5424.SDParam{Name: "eventSource", Value: "Application"},
+	)
+	builder.AddSDParam("origin", "ip", "192.0.2.1")
+	builder.AddSDParam("origin", "software", `test "value" for [RFC5424]`)
 	builder.SetMessage("test message")
 
 	if err := builder.Build(); err != nil {
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/deadspacewii/psyslog/server"
+	"log"
+	"os"
+	"os/signal"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	srv := server.NewUDPServer("127.0.0.1:5514", func(msg *server.Message) {
+		if msg.Err != nil {
+			fmt.Println(msg.Addr, msg.Err)
+			return
+		}
+
+		m := msg.Result.ToMessage()
+		fmt.Println(msg.Addr, msg.Result.Format, m.Hostname, m.AppName, m.Message)
+	})
+
+	if err := srv.ListenAndServe(ctx); err != nil {
+		log.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"log"
+	"testing"
+)
+
+var (
+	rfc3164Log = []byte(`<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8`)
+	rfc5424Log = []byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"] An application event log entry`)
+)
+
+// ParseView fills a reused View with views into the input, the program
+// reports the allocations per message of both parsers next to ParseBytes.
+func main() {
+	parser3164 := rfc3164.NewParser[any, any]()
+	parser5424 := rfc5424.NewParser[any]()
+
+	var (
+		view3164 rfc3164.View
+		view5424 rfc5424.View
+	)
+
+	if err := parser3164.ParseView(rfc3164Log, &view3164); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if err := parser5424.ParseView(rfc5424Log, &view5424); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	fmt.Printf("%s %s %s\n", view3164.Hostname, view3164.AppName, view3164.Content)
+	fmt.Printf("%s %s %s\n", view5424.Hostname, view5424.AppName, view5424.Message)
+
+	benchmarks := []struct {
+		name string
+		f    func()
+	}{
+		{"rfc3164 ParseView", func() { _ = parser3164.ParseView(rfc3164Log, &view3164) }},
+		{"rfc3164 ParseBytes", func() { _, _ = parser3164.ParseBytes(rfc3164Log) }},
+		{"rfc5424 ParseView", func() { _ = parser5424.ParseView(rfc5424Log, &view5424) }},
+		{"rfc5424 ParseBytes", func() { _, _ = parser5424.ParseBytes(rfc5424Log) }},
+	}
+
+	for _, bm := range benchmarks {
+		f := bm.f
+		res := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f()
+			}
+		})
+
+		fmt.Printf("%-20s %8d ns/op %4.0f allocs/op\n", bm.name, res.NsPerOp(), testing.AllocsPerRun(1000, f))
+	}
+}
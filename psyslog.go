@@ -0,0 +1,257 @@
+// Package psyslog parses syslog messages without knowing their format up
+// front, it picks the RFC 3164 or the RFC 5424 parser from the header.
+package psyslog
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"github.com/deadspacewii/psyslog/rfc5424"
+)
+
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatRFC3164
+	FormatRFC5424
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatRFC3164:
+		return "RFC3164"
+	case FormatRFC5424:
+		return "RFC5424"
+	}
+
+	return "unknown"
+}
+
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+type (
+	RFC3164Result = rfc3164.ResultRFC3164[any, any]
+	RFC5424Result = rfc5424.ResultRFC5424[any]
+)
+
+// the parsers are configured once and shared, ParseBytes is safe for
+// concurrent use
+var (
+	rfc3164Parser        = rfc3164.NewParser[any, any]()
+	rfc3164LenientParser = newRFC3164Parser((*rfc3164.Parser[any, any]).WithLenient)
+	rfc3164LocalParser   = newRFC3164Parser((*rfc3164.Parser[any, any]).WithoutHostname)
+	rfc3164PartialParser = newRFC3164Parser((*rfc3164.Parser[any, any]).WithBestEffort)
+	rfc5424Parser        = newRFC5424Parser(withDefaultVersions)
+	rfc5424PartialParser = newRFC5424Parser(func(p *rfc5424.Parser[any]) {
+		withDefaultVersions(p)
+		p.WithBestEffort()
+	})
+)
+
+// ParseFunc turns one raw message into a result.
+type ParseFunc func(b []byte) (*Result, error)
+
+// ParseFuncFor returns the parser of format, FormatUnknown auto-detects the
+// format of every message with Parse.
+func ParseFuncFor(format Format) ParseFunc {
+	switch format {
+	case FormatRFC3164:
+		return ParseRFC3164
+	case FormatRFC5424:
+		return ParseRFC5424
+	}
+
+	return Parse
+}
+
+// Result holds the outcome of Parse, only the field matching Format is set.
+type Result struct {
+	Format  Format         `json:"format"`
+	RFC3164 *RFC3164Result `json:"rfc3164,omitempty"`
+	RFC5424 *RFC5424Result `json:"rfc5424,omitempty"`
+}
+
+// ToMessage converts the parsed result into the format independent
+// common.Message.
+func (r *Result) ToMessage() *common.Message {
+	switch {
+	case r.RFC3164 != nil:
+		return r.RFC3164.ToMessage()
+	case r.RFC5424 != nil:
+		return r.RFC5424.ToMessage()
+	}
+
+	return &common.Message{}
+}
+
+// Detect tells the format of b from its header. An RFC 5424 message has
+// digits followed by a space right after PRI, anything else is taken as RFC
+// 3164. The digits need not be a valid or supported VERSION, so that "0" or
+// a later version is reported by the RFC 5424 parser.
+// https://tools.ietf.org/html/rfc5424#section-6.2.2
+func Detect(b []byte) Format {
+	l := len(b)
+	index := 0
+
+	if _, err := common.ParsePriority(b, &index, l); err != nil {
+		return FormatUnknown
+	}
+
+	start := index
+	for index < l && common.IsDigit(b[index]) {
+		index++
+	}
+
+	if index > start && index < l && b[index] == ' ' {
+		return FormatRFC5424
+	}
+
+	return FormatRFC3164
+}
+
+// Parse parses b with the parser matching its detected format. A message
+// which looks like RFC 5424 but fails to parse is parsed again as lenient
+// RFC 3164 before giving up with the RFC 5424 error. A well formed RFC 5424
+// header with an invalid or unsupported VERSION is not retried, the VERSION
+// error is returned.
+func Parse(b []byte) (*Result, error) {
+	switch Detect(b) {
+	case FormatRFC5424:
+		res, err := ParseRFC5424(b)
+		if err == nil {
+			return res, nil
+		}
+
+		if isVersionError(err) && headerMatches(b) {
+			return nil, err
+		}
+
+		if fallback, e := parseRFC3164(b, true); e == nil {
+			return fallback, nil
+		}
+
+		return nil, err
+	case FormatRFC3164:
+		return ParseRFC3164(b)
+	}
+
+	_, err := common.ParsePriority(b, new(int), len(b))
+	return nil, err
+}
+
+// ParseBestEffort parses b with the parser matching its detected format and
+// returns a result even when parsing fails. It then holds the fields parsed
+// before the failure and the rest of the message as its body, so that a
+// malformed message can still be routed by host and severity.
+func ParseBestEffort(b []byte) (*Result, error) {
+	if Detect(b) == FormatRFC5424 {
+		res, err := rfc5424PartialParser.ParseBytes(b)
+
+		return &Result{
+			Format:  FormatRFC5424,
+			RFC5424: res,
+		}, err
+	}
+
+	res, err := rfc3164PartialParser.ParseBytes(b)
+
+	return &Result{
+		Format:  FormatRFC3164,
+		RFC3164: res,
+	}, err
+}
+
+// ParseRFC3164 parses b as an RFC 3164 message.
+func ParseRFC3164(b []byte) (*Result, error) {
+	return parseRFC3164(b, false)
+}
+
+// ParseRFC5424 parses b as an RFC 5424 message.
+func ParseRFC5424(b []byte) (*Result, error) {
+	res, err := rfc5424Parser.ParseBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Format:  FormatRFC5424,
+		RFC5424: res,
+	}, nil
+}
+
+// ParseLocal parses b as received on /dev/log, glibc syslog(3) writes RFC
+// 3164 without HOSTNAME while logger(1) may also send RFC 5424.
+func ParseLocal(b []byte) (*Result, error) {
+	if Detect(b) == FormatRFC5424 {
+		return ParseRFC5424(b)
+	}
+
+	return dumpRFC3164(rfc3164LocalParser, b)
+}
+
+func newRFC3164Parser(configure func(*rfc3164.Parser[any, any])) *rfc3164.Parser[any, any] {
+	parser := rfc3164.NewParser[any, any]()
+	configure(parser)
+
+	return parser
+}
+
+func withDefaultVersions(p *rfc5424.Parser[any]) {
+	p.WithSupportedVersions(common.DefaultSupportedVersions...)
+}
+
+func isVersionError(err error) bool {
+	return errors.Is(err, common.ErrVersionZero) ||
+		errors.Is(err, common.ErrVersionTooLong) ||
+		errors.Is(err, common.ErrVersionUnsupported)
+}
+
+// headerMatches reports whether the RFC 5424 header of b is well formed
+// once its VERSION is replaced by 1, "<13>42 apples were sold" is RFC 3164
+// content rather than a message of version 42.
+func headerMatches(b []byte) bool {
+	index := 0
+	if _, err := common.ParsePriority(b, &index, len(b)); err != nil {
+		return false
+	}
+
+	end := index
+	for end < len(b) && common.IsDigit(b[end]) {
+		end++
+	}
+
+	header := append(append(b[:index:index], '1'), b[end:]...)
+	_, err := rfc5424Parser.ParseBytes(header)
+
+	return err == nil
+}
+
+func newRFC5424Parser(configure func(*rfc5424.Parser[any])) *rfc5424.Parser[any] {
+	parser := rfc5424.NewParser[any]()
+	configure(parser)
+
+	return parser
+}
+
+func parseRFC3164(b []byte, lenient bool) (*Result, error) {
+	if lenient {
+		return dumpRFC3164(rfc3164LenientParser, b)
+	}
+
+	return dumpRFC3164(rfc3164Parser, b)
+}
+
+func dumpRFC3164(parser *rfc3164.Parser[any, any], b []byte) (*Result, error) {
+	res, err := parser.ParseBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Format:  FormatRFC3164,
+		RFC3164: res,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package psyslog_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/common"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		line string
+		want psyslog.Format
+	}{
+		{"<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", psyslog.FormatRFC5424},
+		{"<165>0 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", psyslog.FormatRFC5424},
+		{"<165>2 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", psyslog.FormatRFC5424},
+		{"<165>1234 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", psyslog.FormatRFC5424},
+		{"<34>Oct 11 22:14:15 mymachine su: 'su root' failed", psyslog.FormatRFC3164},
+		{"<34>1Oct 11 22:14:15 mymachine su: x", psyslog.FormatRFC3164},
+		{"<34>", psyslog.FormatRFC3164},
+		{"no priority", psyslog.FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := psyslog.Detect([]byte(tt.line)); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		line string
+		want error
+	}{
+		{"<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", nil},
+		{"<165>0 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", common.ErrVersionZero},
+		{"<165>2 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", common.ErrVersionUnsupported},
+		{"<165>1234 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x", common.ErrVersionTooLong},
+	}
+
+	for _, tt := range tests {
+		res, err := psyslog.Parse([]byte(tt.line))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.line, err, tt.want)
+			continue
+		}
+
+		if tt.want == nil && res.Format != psyslog.FormatRFC5424 {
+			t.Errorf("%q: got %s, want %s", tt.line, res.Format, psyslog.FormatRFC5424)
+		}
+	}
+
+	var unsupported *common.UnsupportedVersionError
+
+	_, err := psyslog.Parse([]byte("<165>2 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - x"))
+	if !errors.As(err, &unsupported) || unsupported.Version != 2 {
+		t.Errorf("got %v, want an UnsupportedVersionError for version 2", err)
+	}
+}
+
+func TestParseDigitsFallback(t *testing.T) {
+	for _, line := range []string{
+		"<13>42 apples were sold",
+		"<13>0 items left",
+		"<13>2024 was a year",
+		"<13>1 apple",
+		"<165>2 not a header",
+	} {
+		res, err := psyslog.Parse([]byte(line))
+		if err != nil {
+			t.Errorf("%q: %v", line, err)
+			continue
+		}
+
+		if res.Format != psyslog.FormatRFC3164 {
+			t.Errorf("%q: got %s, want %s", line, res.Format, psyslog.FormatRFC3164)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package relp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClientClosed = errors.New("RELP client is closed")
+	ErrRejected     = errors.New("RELP peer rejected the message")
+)
+
+const (
+	// DEFAULTWINDOW is the number of unacknowledged messages in flight.
+	DEFAULTWINDOW = 128
+	// DEFAULTTIMEOUT bounds the wait for a response and every write.
+	DEFAULTTIMEOUT    = 30 * time.Second
+	DEFAULTRETRIES    = 5
+	DEFAULTMINBACKOFF = 100 * time.Millisecond
+	DEFAULTMAXBACKOFF = 5 * time.Second
+)
+
+type pending struct {
+	txnr int
+	data []byte
+}
+
+// Client sends syslog messages over RELP. Up to the window size of messages
+// are in flight, the ones not acknowledged when the connection drops are
+// retransmitted in order on the next one. Client is safe for concurrent
+// use.
+type Client struct {
+	addr       string
+	window     int
+	timeout    time.Duration
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	conn    net.Conn
+	txnr    int
+	pending []*pending
+	err     error
+	closed  bool
+}
+
+// NewClient returns a client for addr, the session is opened on first use.
+func NewClient(addr string) *Client {
+	c := &Client{
+		addr:       addr,
+		window:     DEFAULTWINDOW,
+		timeout:    DEFAULTTIMEOUT,
+		retries:    DEFAULTRETRIES,
+		minBackoff: DEFAULTMINBACKOFF,
+		maxBackoff: DEFAULTMAXBACKOFF,
+	}
+
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *Client) WithWindow(n int) *Client {
+	c.window = n
+	return c
+}
+
+// WithTimeout sets how long a response or a write may take before the
+// connection is considered lost.
+func (c *Client) WithTimeout(d time.Duration) *Client {
+	c.timeout = d
+	return c
+}
+
+// WithBackoff sets how many times connecting is retried and the bounds of
+// the delay doubling between attempts.
+func (c *Client) WithBackoff(retries int, min, max time.Duration) *Client {
+	c.retries = retries
+	c.minBackoff = min
+	c.maxBackoff = max
+	return c
+}
+
+// Send queues msg, it blocks while the window is full. A message rejected
+// by the peer is reported by a later Send or Flush.
+func (c *Client) Send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := append([]byte(nil), msg...)
+
+	for {
+		if c.closed {
+			return ErrClientClosed
+		}
+
+		if err := c.takeErr(); err != nil {
+			return err
+		}
+
+		if c.conn == nil {
+			if err := c.connect(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if len(c.pending) < c.window {
+			break
+		}
+
+		c.cond.Wait()
+	}
+
+	p := &pending{data: data}
+	c.pending = append(c.pending, p)
+
+	// a failed write leaves the message pending, it goes out again once
+	// the reader notices the broken connection and Flush or Send reconnect
+	c.transmit(p)
+
+	return nil
+}
+
+// Flush waits until every message sent so far is acknowledged.
+func (c *Client) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.flush()
+}
+
+// Close flushes, ends the session and closes the connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	err := c.flush()
+
+	c.closed = true
+	c.cond.Broadcast()
+
+	if c.conn != nil {
+		conn := c.conn
+		c.conn = nil
+
+		c.txnr = nextTxnr(c.txnr)
+		conn.SetDeadline(time.Now().Add(c.timeout))
+		writeFrame(conn, &Frame{Txnr: c.txnr, Command: CMDCLOSE})
+		conn.Close()
+	}
+
+	return err
+}
+
+func (c *Client) flush() error {
+	for len(c.pending) > 0 {
+		if c.closed {
+			return ErrClientClosed
+		}
+
+		if c.conn == nil {
+			if err := c.connect(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		c.cond.Wait()
+	}
+
+	return c.takeErr()
+}
+
+func (c *Client) takeErr() error {
+	err := c.err
+	c.err = nil
+	return err
+}
+
+// connect opens a new session and retransmits the pending messages, it is
+// called with mu held.
+func (c *Client) connect() error {
+	backoff := c.minBackoff
+
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		var conn net.Conn
+
+		conn, err = c.open()
+		if err == nil {
+			c.conn = conn
+			break
+		}
+
+		if attempt >= c.retries {
+			return err
+		}
+
+		c.mu.Unlock()
+		time.Sleep(backoff)
+		c.mu.Lock()
+
+		if c.closed {
+			return ErrClientClosed
+		}
+
+		// a concurrent Send or Flush connected meanwhile, a second session
+		// would deliver the pending messages twice
+		if c.conn != nil {
+			return nil
+		}
+
+		backoff *= 2
+		if backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+
+	for _, p := range c.pending {
+		c.transmit(p)
+	}
+
+	go c.readResponses(c.conn, bufio.NewReader(c.conn))
+
+	return nil
+}
+
+// open dials and exchanges the open command, the transaction numbers of a
+// session start at 1.
+func (c *Client) open() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c.txnr = 1
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if err := writeFrame(conn, &Frame{Txnr: c.txnr, Command: CMDOPEN, Data: offers()}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	frame, err := readFrame(bufio.NewReader(conn), MAXDATALEN)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if frame.Command != CMDRSP || frame.Txnr != c.txnr {
+		conn.Close()
+		return nil, ErrResponse
+	}
+
+	code, _, _, err := parseResponse(frame.Data)
+	if err != nil || code != 200 {
+		conn.Close()
+		return nil, ErrRejected
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// transmit writes p with a fresh transaction number, called with mu held.
+// The write deadline keeps a peer which stopped reading from holding mu,
+// the acknowledgements wait for it.
+func (c *Client) transmit(p *pending) {
+	c.txnr = nextTxnr(c.txnr)
+	p.txnr = c.txnr
+
+	deadline := time.Now().Add(c.timeout)
+	c.conn.SetReadDeadline(deadline)
+	c.conn.SetWriteDeadline(deadline)
+
+	if err := writeFrame(c.conn, &Frame{Txnr: p.txnr, Command: CMDSYSLOG, Data: p.data}); err != nil {
+		c.conn.Close()
+	}
+}
+
+// readResponses acknowledges pending messages until conn breaks.
+func (c *Client) readResponses(conn net.Conn, reader *bufio.Reader) {
+	for {
+		frame, err := readFrame(reader, MAXDATALEN)
+
+		c.mu.Lock()
+
+		// a response to no outstanding transaction means the session is
+		// out of step, the pending messages go out again on a new one
+		if err != nil || frame.Command == CMDSERVERCLOSE || frame.Command == CMDRSP && !c.ack(frame) {
+			if c.conn == conn {
+				c.conn = nil
+			}
+
+			conn.Close()
+			c.cond.Broadcast()
+			c.mu.Unlock()
+			return
+		}
+
+		if len(c.pending) == 0 {
+			conn.SetReadDeadline(time.Time{})
+		}
+
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	}
+}
+
+// ack drops the pending message frame answers, it reports false when no
+// message is pending under the transaction number. Called with mu held.
+func (c *Client) ack(frame *Frame) bool {
+	for i, p := range c.pending {
+		if p.txnr != frame.Txnr {
+			continue
+		}
+
+		code, _, _, err := parseResponse(frame.Data)
+		if (err != nil || code != 200) && c.err == nil {
+			c.err = ErrRejected
+		}
+
+		c.pending = append(c.pending[:i], c.pending[i+1:]...)
+		return true
+	}
+
+	return false
+}
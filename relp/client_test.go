@@ -0,0 +1,376 @@
+package relp_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/deadspacewii/psyslog/relp"
+	"github.com/deadspacewii/psyslog/server"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+const relpMessage = "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - An application event"
+
+// startServer serves a RELP server until the test ends.
+func startServer(t *testing.T, handler server.Handler) string {
+	t.Helper()
+
+	srv := relp.NewServer("127.0.0.1:0", handler)
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		srv.Serve(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return srv.Addr().String()
+}
+
+func TestClientWindow(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan string, 3)
+
+	addr := startServer(t, func(msg *server.Message) {
+		received <- string(msg.Raw)
+		<-release
+	})
+
+	c := relp.NewClient(addr).WithWindow(2).WithTimeout(5 * time.Second)
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := c.Send([]byte(relpMessage)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- c.Send([]byte(relpMessage))
+	}()
+
+	select {
+	case err := <-sent:
+		t.Fatalf("third Send returned %v with the window full", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("third Send still blocked after the acknowledgements")
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := <-received; got != relpMessage {
+			t.Fatalf("got %q, want %q", got, relpMessage)
+		}
+	}
+}
+
+type peerFrame struct {
+	txnr    int
+	command string
+	data    []byte
+}
+
+func readPeerFrame(r *bufio.Reader) (peerFrame, error) {
+	var f peerFrame
+	var n int
+
+	if _, err := fmt.Fscan(r, &f.txnr, &f.command, &n); err != nil {
+		return f, err
+	}
+
+	if n > 0 {
+		f.data = make([]byte, n+1)
+		if _, err := io.ReadFull(r, f.data); err != nil {
+			return f, err
+		}
+
+		f.data = f.data[1:]
+	}
+
+	_, err := r.ReadByte()
+	return f, err
+}
+
+func writePeerFrame(w io.Writer, txnr int, command string, data string) error {
+	if data == "" {
+		_, err := fmt.Fprintf(w, "%d %s 0\n", txnr, command)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%d %s %d %s\n", txnr, command, len(data), data)
+	return err
+}
+
+// acceptOpen answers the open command of the next session on l.
+func acceptOpen(l net.Listener) (net.Conn, *bufio.Reader, error) {
+	conn, err := l.Accept()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	f, err := readPeerFrame(reader)
+	if err == nil && f.command != relp.CMDOPEN {
+		err = fmt.Errorf("got %q, want open", f.command)
+	}
+
+	if err == nil {
+		err = writePeerFrame(conn, f.txnr, relp.CMDRSP, "200 OK")
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, reader, nil
+}
+
+func TestClientResend(t *testing.T) {
+	tests := []struct {
+		name string
+		// drop ends the first session after the syslog frame f
+		drop func(conn net.Conn, f peerFrame)
+	}{
+		{
+			name: "connection lost",
+			drop: func(conn net.Conn, f peerFrame) {},
+		},
+		{
+			name: "serverclose",
+			drop: func(conn net.Conn, f peerFrame) {
+				writePeerFrame(conn, 0, relp.CMDSERVERCLOSE, "")
+			},
+		},
+		{
+			name: "response to no transaction",
+			drop: func(conn net.Conn, f peerFrame) {
+				writePeerFrame(conn, f.txnr+100, relp.CMDRSP, "200 OK")
+
+				// the client hangs up on its own
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				io.Copy(io.Discard, conn)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			resent := make(chan string, 1)
+
+			go func() {
+				conn, reader, err := acceptOpen(l)
+				if err != nil {
+					return
+				}
+
+				f, err := readPeerFrame(reader)
+				if err != nil {
+					conn.Close()
+					return
+				}
+
+				tt.drop(conn, f)
+				conn.Close()
+
+				conn, reader, err = acceptOpen(l)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				f, err = readPeerFrame(reader)
+				if err != nil {
+					return
+				}
+
+				resent <- string(f.data)
+				writePeerFrame(conn, f.txnr, relp.CMDRSP, "200 OK")
+				readPeerFrame(reader)
+			}()
+
+			c := relp.NewClient(l.Addr().String()).
+				WithTimeout(5*time.Second).
+				WithBackoff(5, 10*time.Millisecond, 100*time.Millisecond)
+			defer c.Close()
+
+			if err := c.Send([]byte(relpMessage)); err != nil {
+				t.Fatal(err)
+			}
+
+			// well within the response timeout, the drop alone triggers
+			// the resend
+			flushed := make(chan error, 1)
+			go func() {
+				flushed <- c.Flush()
+			}()
+
+			select {
+			case err := <-flushed:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("Flush still waiting for the first session")
+			}
+
+			select {
+			case got := <-resent:
+				if got != relpMessage {
+					t.Fatalf("got %q, want %q", got, relpMessage)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("message not resent")
+			}
+		})
+	}
+}
+
+// ackServer acknowledges every syslog command on l and reports the number
+// of sessions and the messages received.
+func ackServer(l net.Listener, sessions chan<- int, received chan<- string) {
+	for n := 1; ; n++ {
+		conn, reader, err := acceptOpen(l)
+		if err != nil {
+			return
+		}
+
+		sessions <- n
+
+		go func() {
+			defer conn.Close()
+
+			for {
+				f, err := readPeerFrame(reader)
+				if err != nil || f.command != relp.CMDSYSLOG {
+					return
+				}
+
+				received <- string(f.data)
+				writePeerFrame(conn, f.txnr, relp.CMDRSP, "200 OK")
+			}
+		}()
+	}
+}
+
+func TestClientConcurrentReconnect(t *testing.T) {
+	// the address of a server which is down
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	c := relp.NewClient(addr).
+		WithTimeout(5*time.Second).
+		WithBackoff(50, 10*time.Millisecond, 20*time.Millisecond)
+	defer c.Close()
+
+	const senders = 8
+
+	sent := make(chan error, senders)
+	for i := 0; i < senders; i++ {
+		go func(i int) {
+			sent <- c.Send([]byte(fmt.Sprintf("%s %d", relpMessage, i)))
+		}(i)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("can not listen again on %s: %v", addr, err)
+	}
+	defer l.Close()
+
+	sessions := make(chan int, 1024)
+	received := make(chan string, 1024)
+	go ackServer(l, sessions, received)
+
+	for i := 0; i < senders; i++ {
+		select {
+		case err := <-sent:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Send still blocked")
+		}
+	}
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- c.Flush()
+	}()
+
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush still waiting")
+	}
+
+	seen := make(map[string]bool)
+	for len(seen) < senders {
+		select {
+		case msg := <-received:
+			if seen[msg] {
+				t.Fatalf("%q delivered twice", msg)
+			}
+
+			seen[msg] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d messages, want %d", len(seen), senders)
+		}
+	}
+
+	// give a second session the time to show up
+	time.Sleep(50 * time.Millisecond)
+
+	if n := len(sessions); n != 1 {
+		t.Errorf("got %d sessions, want 1", n)
+	}
+
+	select {
+	case msg := <-received:
+		t.Errorf("%q delivered twice", msg)
+	default:
+	}
+}
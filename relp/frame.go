@@ -0,0 +1,229 @@
+// Package relp implements the Reliable Event Logging Protocol used between
+// rsyslog relays, http://www.rsyslog.com/doc/relp.html
+package relp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	CMDOPEN        = "open"
+	CMDCLOSE       = "close"
+	CMDSYSLOG      = "syslog"
+	CMDRSP         = "rsp"
+	CMDSERVERCLOSE = "serverclose"
+
+	// RELPVERSION is the protocol version offered on open.
+	RELPVERSION = 0
+	SOFTWARE    = "psyslog"
+
+	// MAXDATALEN is the default DATA size limit, the same as librelp.
+	MAXDATALEN = 128 * 1024
+
+	// MAXTXNR is the highest transaction number, the next one wraps to 1.
+	MAXTXNR = 999999999
+
+	maxTxnrDigits    = 9
+	maxCommandLen    = 32
+	maxDataLenDigits = 9
+)
+
+var (
+	ErrFrameTxnr      = errors.New("Invalid RELP transaction number")
+	ErrFrameCommand   = errors.New("Invalid RELP command")
+	ErrFrameDataLen   = errors.New("Invalid RELP data length")
+	ErrFrameTooLong   = errors.New("RELP data too long")
+	ErrFrameNoTrailer = errors.New("No trailer found for RELP frame")
+	ErrResponse       = errors.New("Invalid RELP response")
+)
+
+// Frame is a single RELP frame:
+//
+//	RELP-FRAME = TXNR SP COMMAND SP DATALEN [SP DATA] TRAILER
+type Frame struct {
+	Txnr    int
+	Command string
+	Data    []byte
+}
+
+func readFrame(r *bufio.Reader, maxLen int) (*Frame, error) {
+	txnr, end, err := readNumber(r, maxTxnrDigits, ErrFrameTxnr)
+	if err != nil {
+		return nil, err
+	}
+
+	if end != ' ' {
+		return nil, ErrFrameTxnr
+	}
+
+	command, err := readCommand(r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	dataLen, end, err := readNumber(r, maxDataLenDigits, ErrFrameDataLen)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	frame := &Frame{
+		Txnr:    txnr,
+		Command: command,
+	}
+
+	// DATALEN 0 is directly followed by the trailer
+	if end == '\n' {
+		if dataLen != 0 {
+			return nil, ErrFrameDataLen
+		}
+
+		return frame, nil
+	}
+
+	if dataLen > maxLen {
+		return nil, ErrFrameTooLong
+	}
+
+	frame.Data = make([]byte, dataLen)
+	if _, err := io.ReadFull(r, frame.Data); err != nil {
+		return nil, unexpected(err)
+	}
+
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	if c != '\n' {
+		return nil, ErrFrameNoTrailer
+	}
+
+	return frame, nil
+}
+
+// readNumber reads digits up to the SP or LF ending them, io.EOF is only
+// returned when the stream ends before the first digit.
+func readNumber(r *bufio.Reader, maxDigits int, e error) (int, byte, error) {
+	n := 0
+
+	for digits := 0; ; digits++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			if digits == 0 {
+				return 0, 0, err
+			}
+
+			return 0, 0, unexpected(err)
+		}
+
+		if (c == ' ' || c == '\n') && digits > 0 {
+			return n, c, nil
+		}
+
+		if c < '0' || c > '9' || digits >= maxDigits {
+			return 0, 0, e
+		}
+
+		n = n*10 + int(c-'0')
+	}
+}
+
+func readCommand(r *bufio.Reader) (string, error) {
+	var command []byte
+
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", unexpected(err)
+		}
+
+		if c == ' ' && len(command) > 0 {
+			return string(command), nil
+		}
+
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') || len(command) >= maxCommandLen {
+			return "", ErrFrameCommand
+		}
+
+		command = append(command, c)
+	}
+}
+
+func appendFrame(dst []byte, frame *Frame) []byte {
+	dst = strconv.AppendInt(dst, int64(frame.Txnr), 10)
+	dst = append(dst, ' ')
+	dst = append(dst, frame.Command...)
+	dst = append(dst, ' ')
+	dst = strconv.AppendInt(dst, int64(len(frame.Data)), 10)
+
+	if len(frame.Data) > 0 {
+		dst = append(dst, ' ')
+		dst = append(dst, frame.Data...)
+	}
+
+	return append(dst, '\n')
+}
+
+func writeFrame(w io.Writer, frame *Frame) error {
+	_, err := w.Write(appendFrame(nil, frame))
+	return err
+}
+
+// response builds the DATA of a rsp frame, RSP-CODE SP HUMANMSG [LF DATA].
+func response(code int, msg string, data []byte) []byte {
+	rsp := []byte(fmt.Sprintf("%03d %s", code, msg))
+
+	if len(data) > 0 {
+		rsp = append(rsp, '\n')
+		rsp = append(rsp, data...)
+	}
+
+	return rsp
+}
+
+// parseResponse splits the DATA of a rsp frame.
+func parseResponse(data []byte) (int, string, []byte, error) {
+	if len(data) < 3 {
+		return 0, "", nil, ErrResponse
+	}
+
+	code, err := strconv.Atoi(string(data[:3]))
+	if err != nil {
+		return 0, "", nil, ErrResponse
+	}
+
+	rest := data[3:]
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+
+	msg, extra, _ := bytes.Cut(rest, []byte{'\n'})
+
+	return code, string(msg), extra, nil
+}
+
+// offers is the DATA of the open command and of its response.
+func offers() []byte {
+	return []byte(fmt.Sprintf("relp_version=%d\nrelp_software=%s\ncommands=%s", RELPVERSION, SOFTWARE, CMDSYSLOG))
+}
+
+func nextTxnr(txnr int) int {
+	if txnr >= MAXTXNR {
+		return 1
+	}
+
+	return txnr + 1
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,258 @@
+package relp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/server"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrNotOpen = errors.New("RELP session is not open")
+
+// Server accepts RELP sessions, every syslog command is parsed, handed to
+// the handler and acknowledged once the handler returns.
+type Server struct {
+	addr       string
+	handler    server.Handler
+	errHandler server.ErrorHandler
+	parse      server.ParseFunc
+	maxLen     int
+	timeout    time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+	sessions map[*session]struct{}
+	wg       sync.WaitGroup
+}
+
+type session struct {
+	conn    net.Conn
+	timeout time.Duration
+	wmu     sync.Mutex
+}
+
+// send writes frame, a peer which does not read within the timeout fails
+// the write.
+func (s *session) send(frame *Frame) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+
+	return writeFrame(s.conn, frame)
+}
+
+func NewServer(addr string, handler server.Handler) *Server {
+	return &Server{
+		addr:     addr,
+		handler:  handler,
+		parse:    psyslog.Parse,
+		maxLen:   MAXDATALEN,
+		timeout:  DEFAULTTIMEOUT,
+		sessions: make(map[*session]struct{}),
+	}
+}
+
+// WithFormat selects the parser used for every message, FormatUnknown
+// auto-detects the format.
+func (s *Server) WithFormat(format psyslog.Format) {
+	s.parse = server.ParseFuncFor(format)
+}
+
+func (s *Server) WithParseFunc(f server.ParseFunc) {
+	s.parse = f
+}
+
+func (s *Server) WithErrorHandler(h server.ErrorHandler) {
+	s.errHandler = h
+}
+
+// WithMaxDataLen sets the DATA size limit, a session sending more is
+// closed. It defaults to MAXDATALEN.
+func (s *Server) WithMaxDataLen(l int) {
+	s.maxLen = l
+}
+
+// WithWriteTimeout bounds every write to a session, responses and the
+// serverclose sent on shutdown. It defaults to DEFAULTTIMEOUT.
+func (s *Server) WithWriteTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// Listen binds the address, Addr is valid once it returns.
+func (s *Server) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return server.ErrServerListening
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.listener = listener
+	return nil
+}
+
+// Addr returns the bound address, nil before Listen.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+
+	return s.listener.Addr()
+}
+
+// Serve accepts sessions until ctx is done, then sends serverclose to the
+// open sessions and waits for their handlers to return.
+func (s *Server) Serve(ctx context.Context) error {
+	s.mu.Lock()
+	listener := s.listener
+	s.mu.Unlock()
+
+	if listener == nil {
+		return server.ErrServerNotListening
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			listener.Close()
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		s.mu.Lock()
+		sessions := make([]*session, 0, len(s.sessions))
+		for sess := range s.sessions {
+			sessions = append(sessions, sess)
+		}
+		s.mu.Unlock()
+
+		// written outside mu, send bounds each write with the timeout so a
+		// peer which stopped reading can not hang the shutdown
+		for _, sess := range sessions {
+			sess.send(&Frame{Command: CMDSERVERCLOSE})
+			sess.conn.Close()
+		}
+
+		s.wg.Wait()
+
+		s.mu.Lock()
+		s.listener = nil
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		sess := &session{conn: conn, timeout: s.timeout}
+
+		s.mu.Lock()
+		s.sessions[sess] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.sessions, sess)
+				s.mu.Unlock()
+
+				conn.Close()
+			}()
+
+			if err := s.serveSession(sess); err != nil {
+				s.reportError(conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// ListenAndServe binds the address and serves until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	return s.Serve(ctx)
+}
+
+func (s *Server) serveSession(sess *session) error {
+	reader := bufio.NewReader(sess.conn)
+	addr := sess.conn.RemoteAddr()
+	open := false
+
+	for {
+		frame, err := readFrame(reader, s.maxLen)
+		if err != nil {
+			if err == io.EOF || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		var rsp []byte
+
+		switch {
+		case frame.Command == CMDOPEN:
+			open = true
+			rsp = response(200, "OK", offers())
+		case !open:
+			sess.send(&Frame{Txnr: frame.Txnr, Command: CMDRSP, Data: response(500, "session not open", nil)})
+			return ErrNotOpen
+		case frame.Command == CMDSYSLOG:
+			raw := frame.Data
+			res, err := s.parse(raw)
+
+			s.handler(&server.Message{
+				Result: res,
+				Raw:    raw,
+				Addr:   addr,
+				Err:    err,
+			})
+
+			rsp = response(200, "OK", nil)
+		case frame.Command == CMDCLOSE:
+			sess.send(&Frame{Txnr: frame.Txnr, Command: CMDRSP, Data: response(200, "OK", nil)})
+			return nil
+		default:
+			rsp = response(500, "unsupported command", nil)
+		}
+
+		if err := sess.send(&Frame{Txnr: frame.Txnr, Command: CMDRSP, Data: rsp}); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reportError(addr net.Addr, err error) {
+	if s.errHandler != nil {
+		s.errHandler(addr, err)
+	}
+}
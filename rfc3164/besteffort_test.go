@@ -0,0 +1,94 @@
+package rfc3164_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBestEffortKeepsParsedFields(t *testing.T) {
+	stamp := time.Date(2026, 10, 11, 22, 14, 15, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		line      string
+		err       error
+		timestamp time.Time
+		hostname  string
+		content   string
+	}{
+		{
+			name:      "TAG too long",
+			line:      "<34>Oct 11 22:14:15 mymachine " + strings.Repeat("a", 33) + ": hi",
+			err:       common.ErrTagTooLong,
+			timestamp: stamp,
+			hostname:  "mymachine",
+			content:   strings.Repeat("a", 33) + ": hi",
+		},
+		{
+			name:      "extra space before TAG",
+			line:      "<34>Oct 11 22:14:15 mymachine  su: hi",
+			err:       common.ErrExtraSpace,
+			timestamp: stamp,
+			hostname:  "mymachine",
+			content:   "su: hi",
+		},
+		{
+			name:      "extra space before HOSTNAME",
+			line:      "<34>Oct 11 22:14:15  mymachine su: hi",
+			err:       common.ErrExtraSpace,
+			timestamp: stamp,
+			content:   "mymachine su: hi",
+		},
+		{
+			name:      "HOSTNAME outside PRINTUSASCII",
+			line:      "<34>Oct 11 22:14:15 m\xc3\xa9chine su: hi",
+			err:       common.ErrNotPrintUSASCII,
+			timestamp: stamp,
+			content:   "m\xc3\xa9chine su: hi",
+		},
+		{
+			name:     "invalid TIMESTAMP",
+			line:     "<34>Oct 41 22:14:15 mymachine su: hi",
+			err:      common.ErrTimestampUnknownFormat,
+			hostname: "mymachine",
+			content:  "hi",
+		},
+		{
+			name:    "invalid PRI",
+			line:    "<999>Oct 11 22:14:15 mymachine su: hi",
+			err:     common.ErrPriorityBeyondNumber,
+			content: "<999>Oct 11 22:14:15 mymachine su: hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := rfc3164.NewParser[any, any]()
+			p.WithMode(common.ModeStrict)
+			p.WithBestEffort()
+			p.WithLocation("UTC")
+			p.WithClock(fixedClock("2026-10-17T00:00:00Z"))
+
+			res, err := p.ParseBytes([]byte(tt.line))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+
+			if !res.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("timestamp: got %v, want %v", res.Timestamp, tt.timestamp)
+			}
+
+			if res.Hostname != tt.hostname {
+				t.Errorf("hostname: got %q, want %q", res.Hostname, tt.hostname)
+			}
+
+			if res.OriginContent != tt.content {
+				t.Errorf("content: got %q, want %q", res.OriginContent, tt.content)
+			}
+		})
+	}
+}
@@ -30,6 +30,12 @@ func (b *Builder) SetPriority(priority int) *Builder {
 	return b
 }
 
+// SetFacilitySeverity sets the PRI of facility and severity.
+func (b *Builder) SetFacilitySeverity(facility common.Facility, severity common.Severity) *Builder {
+	b.priority = common.PriorityOf(facility, severity)
+	return b
+}
+
 func (b *Builder) SetTimestamp(timestamp string) *Builder {
 	b.timestamp = timestamp
 	return b
@@ -0,0 +1,188 @@
+package rfc3164
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deadspacewii/psyslog/common"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrMissingKey        = errors.New("Missing content key")
+	ErrContentValueNoEnd = errors.New("No end quote found for content value")
+)
+
+// KeyError reports the content key a codec failed on.
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err.Error(), e.Key)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalContent encodes the tagged fields of the struct v as a
+// `key=value` content. Tags are shared with rfc5424.Marshal, only the
+// PARAM-NAME part is used as key. Values holding a space, a quote, a
+// backslash or an equal sign are quoted.
+func MarshalContent(v any) (string, error) {
+	rv, err := common.StructValue(v)
+	if err != nil {
+		return "", err
+	}
+
+	var pairs []string
+
+	for _, field := range common.StructFields(rv.Type()) {
+		values, err := common.FormatValue(rv.FieldByIndex(field.Index), field.Tag.OmitEmpty)
+		if err != nil {
+			return "", &KeyError{Key: field.Tag.Name, Err: err}
+		}
+
+		for _, value := range values {
+			pairs = append(pairs, field.Tag.Name+"="+quoteContentValue(value))
+		}
+	}
+
+	return strings.Join(pairs, " "), nil
+}
+
+// UnmarshalContent decodes a `key=value` content into the struct pointed to
+// by v. Words without an equal sign and keys without a tagged field are
+// skipped, tagged fields without omitempty must be present.
+func UnmarshalContent(s string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return common.ErrCodecNotPointer
+	}
+
+	rv = rv.Elem()
+
+	values, err := splitContent(s)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+
+	for _, field := range common.StructFields(rv.Type()) {
+		key := field.Tag.Name
+
+		vs, ok := values[key]
+		if !ok {
+			if !field.Tag.OmitEmpty && firstErr == nil {
+				firstErr = &KeyError{Key: key, Err: ErrMissingKey}
+			}
+			continue
+		}
+
+		if err := common.SetValue(rv.FieldByIndex(field.Index), vs); err != nil && firstErr == nil {
+			firstErr = &KeyError{Key: key, Err: err}
+		}
+	}
+
+	return firstErr
+}
+
+// UnmarshalContentFunc is a ContentFunc decoding the origin content with
+// UnmarshalContent, NewParser installs it when D has `syslog` tagged fields.
+func UnmarshalContentFunc[D any](s string) (D, error) {
+	var d D
+
+	rv := reflect.ValueOf(&d).Elem()
+	if rv.Kind() == reflect.Pointer {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		return d, UnmarshalContent(s, rv.Interface())
+	}
+
+	return d, UnmarshalContent(s, &d)
+}
+
+func quoteContentValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \"\\=") {
+		return value
+	}
+
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+
+		sb.WriteByte(value[i])
+	}
+
+	sb.WriteByte('"')
+
+	return sb.String()
+}
+
+func splitContent(s string) (map[string][]string, error) {
+	values := make(map[string][]string)
+	l := len(s)
+
+	for i := 0; i < l; {
+		if s[i] == ' ' {
+			i++
+			continue
+		}
+
+		from := i
+		for i < l && s[i] != ' ' && s[i] != '=' {
+			i++
+		}
+
+		if i >= l || s[i] != '=' {
+			continue
+		}
+
+		key := s[from:i]
+		i++
+
+		var value []byte
+
+		if i < l && s[i] == '"' {
+			i++
+
+			closed := false
+			for ; i < l; i++ {
+				c := s[i]
+
+				if c == '\\' && i+1 < l {
+					i++
+					value = append(value, s[i])
+					continue
+				}
+
+				if c == '"' {
+					i++
+					closed = true
+					break
+				}
+
+				value = append(value, c)
+			}
+
+			if !closed {
+				return nil, &KeyError{Key: key, Err: ErrContentValueNoEnd}
+			}
+		} else {
+			for ; i < l && s[i] != ' '; i++ {
+				value = append(value, s[i])
+			}
+		}
+
+		values[key] = append(values[key], string(value))
+	}
+
+	return values, nil
+}
@@ -0,0 +1,74 @@
+package rfc3164_test
+
+import (
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// the tags are the ones of rfc5424.Marshal, only the PARAM-NAME is a key
+type login struct {
+	User    string        `syslog:"login,user"`
+	Source  net.IP        `syslog:"login,src"`
+	Success bool          `syslog:"login,success"`
+	Tries   int           `syslog:"login,tries"`
+	Elapsed time.Duration `syslog:"login,elapsed,omitempty"`
+	Groups  []string      `syslog:"login,group,omitempty"`
+	Note    string        `syslog:"login,note,omitempty"`
+}
+
+// a struct encoded by MarshalContent and built into a message decodes back
+// to the same values
+func TestMarshalContentRoundTrip(t *testing.T) {
+	tests := []login{
+		{
+			User:    "lonvick",
+			Source:  net.IPv4(192, 0, 2, 1),
+			Success: true,
+			Tries:   3,
+			Elapsed: 2 * time.Second,
+			Groups:  []string{"wheel", "adm"},
+			Note:    `said "su root" = a\b`,
+		},
+		{
+			User:   "",
+			Source: net.ParseIP("2001:db8::1"),
+		},
+	}
+
+	for _, want := range tests {
+		content, err := rfc3164.MarshalContent(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b := rfc3164.NewBuilder().
+			SetPriority(34).
+			SetTimestamp("Oct 11 22:14:15").
+			SetHostName("mymachine").
+			SetTag("su").
+			SetDelimiter(':').
+			SetContent(" " + content)
+
+		if err := b.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := rfc3164.NewParser[any, login]().ParseBytes([]byte(b.String()))
+		if err != nil || res.ContentError != nil {
+			t.Fatal(b.String(), err, res.ContentError)
+		}
+
+		got := res.Content
+		if !got.Source.Equal(want.Source) {
+			t.Errorf("got %v, want %v", got.Source, want.Source)
+		}
+
+		got.Source = want.Source
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", b.String(), got, want)
+		}
+	}
+}
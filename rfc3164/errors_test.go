@@ -0,0 +1,118 @@
+package rfc3164_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		line     string
+		mode     common.Mode
+		err      error
+		field    string
+		offset   int
+		snippet  string
+		expected string
+	}{
+		{
+			line:     "Oct 11 22:14:15 mymachine su: x",
+			err:      common.ErrPriorityNoStart,
+			field:    "PRI",
+			offset:   0,
+			snippet:  "Oct 11 22:14:15 ",
+			expected: `"<" PRIVAL ">"`,
+		},
+		{
+			line:     "<34",
+			err:      common.ErrPriorityNoEnd,
+			field:    "PRI",
+			offset:   3,
+			expected: `"<" PRIVAL ">"`,
+		},
+		{
+			line:     "<3a>Oct 11 22:14:15 mymachine su: x",
+			err:      common.ErrPriorityNonDigit,
+			field:    "PRI",
+			offset:   2,
+			snippet:  "a>Oct 11 22:14:1",
+			expected: `"<" PRIVAL ">"`,
+		},
+		{
+			line:     "<999>Oct 11 22:14:15 mymachine su: x",
+			err:      common.ErrPriorityBeyondNumber,
+			field:    "PRI",
+			offset:   1,
+			snippet:  "999>Oct 11 22:14",
+			expected: "PRIVAL 0 to 191 without leading zero",
+		},
+		{
+			line:     "<34>Foo 11 22:14:15 mymachine su: x",
+			err:      common.ErrTimestampUnknownFormat,
+			field:    "TIMESTAMP",
+			offset:   4,
+			snippet:  "Foo 11 22:14:15 ",
+			expected: "Mmm dd hh:mm:ss",
+		},
+		{
+			line:     "<34>Oct 11 22:14:15 mymachine " + strings.Repeat("a", 33) + ": x",
+			mode:     common.ModeStrict,
+			err:      common.ErrTagTooLong,
+			field:    "TAG",
+			offset:   62,
+			snippet:  "a: x",
+			expected: ":",
+		},
+	}
+
+	for _, tt := range tests {
+		p := rfc3164.NewParser[any, any]()
+		p.WithMode(tt.mode)
+
+		_, err := p.ParseBytes([]byte(tt.line))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: got %v, want %v", tt.line, err, tt.err)
+			continue
+		}
+
+		var pe *common.ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: got %T, want a ParseError", tt.line, err)
+			continue
+		}
+
+		if pe.Field != tt.field || pe.Offset != tt.offset || pe.Snippet != tt.snippet || pe.Expected != tt.expected {
+			t.Errorf("%q: got %s at %d near %q expected %q, want %s at %d near %q expected %q",
+				tt.line, pe.Field, pe.Offset, pe.Snippet, pe.Expected, tt.field, tt.offset, tt.snippet, tt.expected)
+		}
+
+		if errors.Unwrap(err) != tt.err {
+			t.Errorf("%q: unwraps to %v, want %v", tt.line, errors.Unwrap(err), tt.err)
+		}
+	}
+}
+
+// every timestamp failure is described with the same grammar
+func TestTimestampExpected(t *testing.T) {
+	for _, line := range []string{
+		"<34>Foo 11 22:14:15 mymachine su: x",
+		"<34>Feb 30 22:14:15 mymachine su: x",
+		"<34>Feb  3 25:14:15 mymachine su: x",
+		"<34>Feb 30 2026 22:14:15 mymachine su: x",
+	} {
+		_, err := rfc3164.NewParser[any, any]().ParseBytes([]byte(line))
+
+		var pe *common.ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: got %v, want a ParseError", line, err)
+			continue
+		}
+
+		if pe.Field != "TIMESTAMP" || pe.Expected != "Mmm dd hh:mm:ss" {
+			t.Errorf("%q: got %s expected %q", line, pe.Field, pe.Expected)
+		}
+	}
+}
@@ -3,8 +3,9 @@ package rfc3164
 import (
 	"bytes"
 	"github.com/deadspacewii/psyslog/common"
-	"math"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,53 +13,94 @@ type TagFunc[T any] func(string) (T, error)
 
 type ContentFunc[D any] func(string) (D, error)
 
+// Parser holds the configuration of RFC 3164 parsing, the With methods
+// panic with common.ErrParserInUse once it parsed a message. ParseBytes
+// keeps its state on the stack so one configured Parser can serve many
+// goroutines.
 type Parser[T any, D any] struct {
-	buff                  []byte
-	index                 int
-	l                     int
-	priority              *common.Priority
-	header                *header
-	message               *message
 	location              *time.Location
 	customTagDelimiter    byte
 	customTimestampFormat string
 	customTagFunc         TagFunc[T]
 	customContentFunc     ContentFunc[D]
+	lenient               bool
+	noHostname            bool
+	years                 yearInference
+	rules                 common.Rules
+	defaultPriority       int
+	inferPriority         bool
+	bestEffort            bool
+	result                *ResultRFC3164[T, D]
+	used                  atomic.Bool
 }
 
 type ResultRFC3164[T any, D any] struct {
-	Priority      int       `json:"priority"`
-	Facility      int       `json:"facility"`
-	Severity      int       `json:"severity"`
-	Timestamp     time.Time `json:"timestamp"`
-	Hostname      string    `json:"hostname"`
-	OriginTag     string    `json:"origin_tag"`
-	OriginContent string    `json:"origin_content"`
-	Tag           T         `json:"tag"`
-	TagError      error     `json:"tag_error"`
-	Content       D         `json:"content"`
-	ContentError  error     `json:"content_error"`
+	Priority         int             `json:"priority"`
+	Facility         common.Facility `json:"facility"`
+	Severity         common.Severity `json:"severity"`
+	PriorityInferred bool            `json:"priority_inferred"`
+	Timestamp        time.Time       `json:"timestamp"`
+	Hostname         string          `json:"hostname"`
+	OriginTag        string          `json:"origin_tag"`
+	AppName          string          `json:"app_name"`
+	ProcId           string          `json:"proc_id"`
+	OriginContent    string          `json:"origin_content"`
+	Tag              T               `json:"tag"`
+	TagError         error           `json:"tag_error"`
+	Content          D               `json:"content"`
+	ContentError     error           `json:"content_error"`
 }
 
-type header struct {
-	timestamp time.Time
-	hostname  string
+// cursor is the state of a single ParseBytes call, field is the index at
+// which the field being parsed starts.
+type cursor struct {
+	buff  []byte
+	index int
+	field int
+	l     int
 }
 
-type message struct {
-	tag     string
-	content string
+// fail reports err at the current index.
+func (c *cursor) fail(field, expected string, err error) error {
+	return common.NewParseError(c.buff[:c.l], c.index, field, expected, err)
 }
 
 func NewParser[T any, D any]() *Parser[T, D] {
-	return &Parser[T, D]{}
+	p := &Parser[T, D]{
+		rules:           common.DefaultRules,
+		defaultPriority: common.DEFAULTPRIORITY,
+	}
+
+	if common.HasTaggedFields(reflect.TypeOf((*D)(nil)).Elem()) {
+		p.customContentFunc = UnmarshalContentFunc[D]
+	}
+
+	return p
+}
+
+// configure panics once p parsed a message, a concurrent ParseBytes would
+// see the configuration change under it.
+func (p *Parser[T, D]) configure() {
+	if p.used.Load() {
+		panic(common.ErrParserInUse)
+	}
+}
+
+// freeze makes the configuration of p read-only, the flag is only written
+// once so that parsers sharing p do not contend on it.
+func (p *Parser[T, D]) freeze() {
+	if !p.used.Load() {
+		p.used.Store(true)
+	}
 }
 
 func (p *Parser[T, D]) WithTimestampFormat(s string) {
+	p.configure()
 	p.customTimestampFormat = s
 }
 
 func (p *Parser[T, D]) WithLocation(location string) {
+	p.configure()
 	switch location {
 	case "UTC":
 		p.location = time.UTC
@@ -73,66 +115,165 @@ func (p *Parser[T, D]) WithLocation(location string) {
 }
 
 func (p *Parser[T, D]) WithTagDelimiter(s byte) {
+	p.configure()
 	p.customTagDelimiter = s
 }
 
+// WithYear gives a TIMESTAMP without year the year y, for a replayed file
+// whose year is known.
+func (p *Parser[T, D]) WithYear(y int) {
+	p.configure()
+	p.years.year = y
+}
+
+// WithReferenceTime gives a TIMESTAMP without year the latest year which
+// does not date it after t, give or take YEARTOLERANCE. t is the
+// modification time of a replayed file for instance.
+func (p *Parser[T, D]) WithReferenceTime(t time.Time) {
+	p.configure()
+	p.years.reference = t
+}
+
+// WithClock replaces time.Now for the reception time and as the reference
+// time of a TIMESTAMP without year, a fixed clock makes results
+// reproducible.
+func (p *Parser[T, D]) WithClock(clock func() time.Time) {
+	p.years.clock = clock
+}
+
+// WithLenient makes Parse accept a message without a valid HEADER the way
+// a relay does, see https://tools.ietf.org/html/rfc3164#section-4.3.3.
+// The reception time is used as TIMESTAMP, HOSTNAME is left empty and
+// everything after PRI becomes the CONTENT.
+func (p *Parser[T, D]) WithLenient() {
+	p.configure()
+	p.lenient = true
+}
+
+// WithoutHostname parses the local format written to /dev/log by glibc
+// syslog(3), where the TIMESTAMP is directly followed by the TAG.
+func (p *Parser[T, D]) WithoutHostname() {
+	p.configure()
+	p.noHostname = true
+}
+
+// WithMode sets the rules to those of a preset. ModeLenient only relaxes
+// the grammar rules, WithLenient also accepts a message without HEADER.
+func (p *Parser[T, D]) WithMode(mode common.Mode) {
+	p.configure()
+	p.rules = mode.Rules()
+}
+
+// WithRules sets the rules enforced by the parser, for instance
+// common.StrictRules &^ common.RulePrintUSASCII.
+func (p *Parser[T, D]) WithRules(rules common.Rules) {
+	p.configure()
+	p.rules = rules
+}
+
+// WithDefaultPriority accepts a message without PRI whatever the rules, as
+// written by logger(1) and some relays, and gives it pri. The result is
+// flagged PriorityInferred. A pri beyond 191 is ignored.
+func (p *Parser[T, D]) WithDefaultPriority(pri int) {
+	p.configure()
+	if common.CheckPriority(pri) == nil {
+		p.defaultPriority = pri
+		p.inferPriority = true
+	}
+}
+
+// WithBestEffort makes ParseBytes return a result along with its error, it
+// holds the fields parsed before the failure and the unparsed remainder as
+// CONTENT. A TIMESTAMP which can not be decoded is left zero.
+func (p *Parser[T, D]) WithBestEffort() {
+	p.configure()
+	p.bestEffort = true
+}
+
 func (p *Parser[T, D]) WithTagFunc(t TagFunc[T]) {
+	p.configure()
 	p.customTagFunc = t
 }
 
 func (p *Parser[T, D]) WithContentFunc(d ContentFunc[D]) {
+	p.configure()
 	p.customContentFunc = d
 }
 
+// Parse parses s and keeps the result for Dump. Parse and Dump are not safe
+// for concurrent use, share a Parser through ParseBytes instead.
 func (p *Parser[T, D]) Parse(s string) error {
-	buff := []byte(s)
-	p.buff = buff
-	p.l = int(math.Min(MAXPACKETLEN, float64(len(buff))))
-	p.index = 0
+	res, err := p.ParseBytes([]byte(s))
+	p.result = res
 
-	pri, err := p.parsePriority()
-	if err != nil {
-		return err
+	return err
+}
+
+// Dump returns the result of the last Parse, an empty result when it failed
+// unless WithBestEffort is set.
+func (p *Parser[T, D]) Dump() *ResultRFC3164[T, D] {
+	if p.result == nil {
+		return &ResultRFC3164[T, D]{}
 	}
 
-	p.priority = pri
+	res := *p.result
 
-	hdr, err := p.parseHeader()
-	if err != nil {
-		return err
-	}
+	return &res
+}
 
-	p.header = hdr
+// ParseBytes parses b and returns its result, the result does not refer to
+// b once ParseBytes returns. It is safe for concurrent use. The result is
+// nil on error unless WithBestEffort is set.
+func (p *Parser[T, D]) ParseBytes(b []byte) (*ResultRFC3164[T, D], error) {
+	var (
+		v  View
+		ts time.Time
+	)
 
-	if p.buff[p.index] == ' ' {
-		p.index++
+	err := p.ParseView(b, &v)
+	if err != nil && !p.bestEffort {
+		return nil, err
 	}
 
-	msg, err := p.parsemessage()
-	if err != common.ErrEOL {
-		return err
+	// a TIMESTAMP delimited before a later failure is still decoded, its
+	// error comes first as it comes first in the message
+	if v.Timestamp != nil {
+		var tsErr error
+		ts, tsErr = v.Time()
+
+		switch {
+		case tsErr == nil:
+		case p.lenient && err == nil:
+			p.fallback(&cursor{buff: b, index: v.header, l: v.l}, &v)
+		case !p.bestEffort:
+			return nil, tsErr
+		default:
+			ts = time.Time{}
+			err = tsErr
+		}
 	}
 
-	p.message = msg
-
-	return nil
-}
+	if v.Timestamp == nil && err == nil {
+		ts = p.receptionTime()
+	}
 
-func (p *Parser[T, D]) Dump() *ResultRFC3164[T, D] {
 	res := ResultRFC3164[T, D]{
-		Priority:      p.priority.Priority,
-		Facility:      p.priority.Facility,
-		Severity:      p.priority.Severity,
-		Timestamp:     p.header.timestamp,
-		Hostname:      p.header.hostname,
-		OriginTag:     p.message.tag,
-		OriginContent: p.message.content,
-		TagError:      nil,
-		ContentError:  nil,
+		Priority:         v.Priority,
+		Facility:         v.Facility,
+		Severity:         v.Severity,
+		PriorityInferred: v.PriorityInferred,
+		Timestamp:        ts,
+		Hostname:         string(v.Hostname),
+		OriginTag:        string(v.Tag),
+		AppName:          string(v.AppName),
+		ProcId:           string(v.ProcId),
+		OriginContent:    string(v.Content),
+		TagError:         nil,
+		ContentError:     nil,
 	}
 
 	if p.customTagFunc != nil {
-		tag, err := p.customTagFunc(p.message.tag)
+		tag, err := p.customTagFunc(res.OriginTag)
 		if err != nil {
 			res.TagError = err
 		} else {
@@ -141,7 +282,7 @@ func (p *Parser[T, D]) Dump() *ResultRFC3164[T, D] {
 	}
 
 	if p.customContentFunc != nil {
-		content, err := p.customContentFunc(p.message.content)
+		content, err := p.customContentFunc(res.OriginContent)
 		if err != nil {
 			res.ContentError = err
 		} else {
@@ -149,159 +290,256 @@ func (p *Parser[T, D]) Dump() *ResultRFC3164[T, D] {
 		}
 	}
 
-	return &res
+	return &res, err
 }
 
-func (p *Parser[T, D]) parsePriority() (*common.Priority, error) {
-	return common.ParsePriority(
-		p.buff, &p.index, p.l,
-	)
+// ToMessage converts the result into the format independent common.Message,
+// APP-NAME and PROCID are the ones split out of the TAG.
+func (r *ResultRFC3164[T, D]) ToMessage() *common.Message {
+	return &common.Message{
+		Priority:         r.Priority,
+		Facility:         r.Facility,
+		Severity:         r.Severity,
+		PriorityInferred: r.PriorityInferred,
+		Timestamp:        r.Timestamp,
+		Hostname:         r.Hostname,
+		AppName:          r.AppName,
+		ProcID:           r.ProcId,
+		Message:          r.OriginContent,
+	}
 }
 
-// HEADER: TIMESTAMP + HOSTNAME (or IP)
-// https://tools.ietf.org/html/rfc3164#section-4.1.2
-func (p *Parser[T, D]) parseHeader() (*header, error) {
-	var err error
+// splitTag splits the conventional program[pid] TAG, a TAG without a
+// bracketed pid is returned whole as program.
+func splitTag(tag []byte) ([]byte, []byte) {
+	tag = bytes.TrimSpace(tag)
 
-	if p.buff[p.index] == ' ' {
-		p.index++
+	open := bytes.IndexByte(tag, '[')
+	if open <= 0 || tag[len(tag)-1] != ']' {
+		return tag, nil
 	}
 
-	ts, err := p.parseTimestamp()
-	if err != nil {
-		return nil, err
+	return tag[:open], tag[open+1 : len(tag)-1]
+}
+
+func (p *Parser[T, D]) parsePriority(c *cursor, v *View) error {
+	if p.missingPriority(c) {
+		v.Priority = p.defaultPriority
+		v.Facility = common.Facility(p.defaultPriority / 8)
+		v.Severity = common.Severity(p.defaultPriority % 8)
+		v.PriorityInferred = true
+
+		return nil
 	}
 
-	h, err := p.parseHostname()
+	pri, err := common.ParsePriorityValue(
+		c.buff, &c.index, c.l,
+	)
 	if err != nil {
-		return nil, err
+		return c.fail("PRI", `"<" PRIVAL ">"`, err)
+	}
+
+	if err := p.rules.CheckPriority(c.buff[:c.l], pri); err != nil {
+		return common.NewParseError(c.buff[:c.l], 1, "PRI", "PRIVAL 0 to 191 without leading zero", err)
 	}
 
-	hdr := &header{
-		timestamp: ts,
-		hostname:  h,
+	v.Priority = pri
+	v.Facility = common.Facility(pri / 8)
+	v.Severity = common.Severity(pri % 8)
+
+	return nil
+}
+
+// missingPriority reports whether the message has no PRI and the default
+// priority is to be assumed.
+func (p *Parser[T, D]) missingPriority(c *cursor) bool {
+	if p.rules.Has(common.RulePriority) && !p.inferPriority {
+		return false
 	}
 
-	return hdr, nil
+	return c.l > 0 && c.buff[0] != '<'
 }
 
-func (p *Parser[T, D]) parseTimestamp() (time.Time, error) {
-	var ts time.Time
+// HEADER: TIMESTAMP + HOSTNAME (or IP)
+// https://tools.ietf.org/html/rfc3164#section-4.1.2
+//
+// The TIMESTAMP immediately follows PRI, a single SP follows the TIMESTAMP
+// and the HOSTNAME.
+func (p *Parser[T, D]) parseHeader(c *cursor, v *View) error {
 	var err error
-	var tsFmtLen int
-	var sub []byte
 
-	tsFmts := []string{
-		"Jan 02 15:04:05",
-		"Jan 02 2006 15:04:05",
+	if err = p.parseSpace(c, "TIMESTAMP", 0); err != nil {
+		return err
 	}
 
-	if p.customTimestampFormat != "" {
-		tsFmts = []string{
-			p.customTimestampFormat,
-		}
+	c.field = c.index
+	if err = p.parseTimestamp(c, v); err != nil {
+		return err
 	}
 
-	found := false
-	for _, tsFmt := range tsFmts {
-		tsFmtLen = len(tsFmt)
+	if p.noHostname {
+		return p.parseSpace(c, "TAG", 1)
+	}
 
-		if p.index+tsFmtLen > p.l {
-			continue
-		}
+	if err = p.parseSpace(c, "HOSTNAME", 1); err != nil {
+		return err
+	}
 
-		sub = p.buff[p.index : tsFmtLen+p.index]
-		if p.location != nil {
-			n := strings.LastIndex(tsFmt, "-07")
-			timeStamp := string(sub)
-			localTimes := ""
-			if n != -1 {
-				localTimes = timeStamp[:n]
-				tsFmt = tsFmt[:n]
-			} else {
-				localTimes = timeStamp
-			}
-			ts, err = time.ParseInLocation(
-				tsFmt, localTimes, p.location,
-			)
-		} else {
-			ts, err = time.Parse(
-				tsFmt, string(sub),
-			)
-		}
+	c.field = c.index
+	v.Hostname, err = p.parseHostname(c)
+	if err != nil {
+		return err
+	}
 
-		if err == nil {
-			found = true
-			break
-		}
+	return p.parseSpace(c, "TAG", 1)
+}
+
+// parseSpace steps over the spaces before field, RuleSingleSpace requires
+// exactly want of them unless the line ends.
+func (p *Parser[T, D]) parseSpace(c *cursor, field string, want int) error {
+	from := c.index
+	c.field = from
+
+	for c.index < c.l && c.buff[c.index] == ' ' {
+		c.index++
 	}
 
-	if !found {
-		return ts, common.ErrTimestampUnknownFormat
+	if !p.rules.Has(common.RuleSingleSpace) || c.index >= c.l {
+		return nil
+	}
+
+	switch n := c.index - from; {
+	case n < want:
+		return c.fail(field, "SP", common.ErrNoSpace)
+	case n > want:
+		c.index = from + want
+		return c.fail(field, field, common.ErrExtraSpace)
 	}
 
-	fixTimestampIfNeeded(&ts)
+	return nil
+}
 
-	p.index += tsFmtLen
+// fallback turns everything from the index on into the CONTENT of a
+// message without a valid HEADER.
+func (p *Parser[T, D]) fallback(c *cursor, v *View) {
+	v.Timestamp = nil
+	v.Hostname = nil
+	v.Tag = nil
+	v.AppName = nil
+	v.ProcId = nil
+	v.Content = bytes.Trim(c.buff[c.index:c.l], " ")
+}
 
-	if (p.index < p.l) && (p.buff[p.index] == ' ') {
-		p.index++
+func (p *Parser[T, D]) receptionTime() time.Time {
+	now := p.years.now()
+	if p.location != nil {
+		now = now.In(p.location)
 	}
 
-	return ts, nil
+	return now
 }
 
-func fixTimestampIfNeeded(ts *time.Time) {
-	now := time.Now()
-	y := ts.Year()
-
-	if ts.Year() == 0 {
-		y = now.Year()
+// parseTimestamp delimits the TIMESTAMP, View.Time decodes it.
+func (p *Parser[T, D]) parseTimestamp(c *cursor, v *View) error {
+	layout, n := matchTimestamp(c.buff[c.index:c.l], p.customTimestampFormat)
+	if n == 0 {
+		return c.fail("TIMESTAMP", timestampExpected(p.customTimestampFormat), common.ErrTimestampUnknownFormat)
 	}
 
-	newTs := time.Date(
-		y, ts.Month(), ts.Day(),
-		ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(),
-		ts.Location(),
-	)
+	v.Timestamp = c.buff[c.index : c.index+n]
+	v.timestamp = c.index
+	v.layout = layout
+	v.location = p.location
+	v.years = &p.years
 
-	*ts = newTs
-}
+	c.index += n
 
-func (p *Parser[T, D]) parseHostname() (string, error) {
-	return common.ParseHostname(
-		p.buff, &p.index, p.l,
-	)
+	return nil
 }
 
-// MSG: TAG + CONTENT
-// https://tools.ietf.org/html/rfc3164#section-4.1.3
-func (p *Parser[T, D]) parsemessage() (*message, error) {
+// parseTimestamp decodes sub with layout, a timestamp without a year gets
+// the one inferred by years.
+func parseTimestamp(sub []byte, layout string, location *time.Location, years *yearInference) (time.Time, error) {
+	var ts time.Time
 	var err error
 
-	tag, err := p.parseTag()
+	if location != nil {
+		n := strings.LastIndex(layout, "-07")
+		timeStamp := string(sub)
+		localTimes := ""
+		if n != -1 {
+			localTimes = timeStamp[:n]
+			layout = layout[:n]
+		} else {
+			localTimes = timeStamp
+		}
+		ts, err = time.ParseInLocation(
+			layout, localTimes, location,
+		)
+	} else {
+		ts, err = time.Parse(
+			layout, string(sub),
+		)
+	}
+
 	if err != nil {
-		return nil, err
+		return ts, common.ErrTimestampUnknownFormat
 	}
 
-	content, err := p.parseContent()
-	if err != common.ErrEOL {
+	return years.complete(ts), nil
+}
+
+func (p *Parser[T, D]) parseHostname(c *cursor) ([]byte, error) {
+	from := c.index
+
+	hostname, _ := common.ScanHostname(
+		c.buff, &c.index, c.l,
+	)
+
+	if err := p.checkPrintUSASCII(c, "HOSTNAME", from); err != nil {
 		return nil, err
 	}
 
-	msg := &message{
-		tag:     tag,
-		content: content,
+	return hostname, nil
+}
+
+// checkPrintUSASCII enforces RulePrintUSASCII on the field from from to the
+// index.
+func (p *Parser[T, D]) checkPrintUSASCII(c *cursor, field string, from int) error {
+	if !p.rules.Has(common.RulePrintUSASCII) {
+		return nil
+	}
+
+	for i := from; i < c.index; i++ {
+		if !common.IsPrintUSASCII(c.buff[i]) {
+			c.index = i
+			return c.fail(field, "PRINTUSASCII", common.ErrNotPrintUSASCII)
+		}
+	}
+
+	return nil
+}
+
+// MSG: TAG + CONTENT
+// https://tools.ietf.org/html/rfc3164#section-4.1.3
+func (p *Parser[T, D]) parsemessage(c *cursor, v *View) error {
+	tag, err := p.parseTag(c)
+	if err != nil {
+		return err
 	}
 
-	return msg, err
+	v.Tag = tag
+	v.AppName, v.ProcId = splitTag(v.Tag)
+	v.Content = p.parseContent(c)
+
+	return nil
 }
 
 // http://tools.ietf.org/html/rfc3164#section-4.1.3
-func (p *Parser[T, D]) parseTag() (string, error) {
-	var b byte
-	var tag []byte
-	var err error
+//
+// The TAG ends at the delimiter. Spaces are only taken as part of it within
+// its first 32 chars, a message without delimiter has no TAG.
+func (p *Parser[T, D]) parseTag(c *cursor) ([]byte, error) {
 	var delimiter byte
 
 	if p.customTagDelimiter == 0 {
@@ -310,45 +548,53 @@ func (p *Parser[T, D]) parseTag() (string, error) {
 		delimiter = p.customTagDelimiter
 	}
 
-	previous := p.index
+	from := c.index
+	to := -1
 
-	// "The TAG is a string of ABNF alphanumeric characters that MUST NOT exceed 32 characters."
-	to := int(
-		math.Min(
-			float64(p.l),
-			float64(p.index+32),
-		),
-	)
-
-	for p.index < to {
-		b = p.buff[p.index]
+	for i := from; i < c.l; i++ {
+		b := c.buff[i]
 
 		if b == delimiter {
-			p.index++
+			to = i
+			break
+		}
+
+		if b == ' ' && (i-from >= 32 || p.rules.Has(common.RulePrintUSASCII)) {
 			break
 		}
+	}
 
-		tag = append(tag, b)
-		p.index++
+	if to <= from {
+		return nil, nil
 	}
 
-	if len(tag) == 0 {
-		p.index = previous
+	// "The TAG is a string of ABNF alphanumeric characters that MUST NOT exceed 32 characters."
+	if p.rules.Has(common.RuleFieldLength) && to-from > 32 {
+		c.index = from + 32
+		return nil, c.fail("TAG", string(delimiter), common.ErrTagTooLong)
 	}
 
-	return string(tag), err
+	c.index = to
+
+	if err := p.checkPrintUSASCII(c, "TAG", from); err != nil {
+		return nil, err
+	}
+
+	c.index++
+
+	return c.buff[from:to], nil
 }
 
-func (p *Parser[T, D]) parseContent() (string, error) {
-	if p.index > p.l {
-		return "", common.ErrEOL
+func (p *Parser[T, D]) parseContent(c *cursor) []byte {
+	if c.index > c.l {
+		return nil
 	}
 
 	content := bytes.Trim(
-		p.buff[p.index:p.l], " ",
+		c.buff[c.index:c.l], " ",
 	)
 
-	p.index += len(content)
+	c.index += len(content)
 
-	return string(content), common.ErrEOL
+	return content
 }
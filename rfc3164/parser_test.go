@@ -0,0 +1,102 @@
+package rfc3164_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// run with -race, one configured parser serves every goroutine
+func TestParseBytesConcurrent(t *testing.T) {
+	p := rfc3164.NewParser[any, any]()
+	p.WithLocation("UTC")
+	p.WithClock(fixedClock("2026-03-01T00:00:00Z"))
+
+	lines := []string{
+		validMessage,
+		"<13>Feb 28 23:59:59 host app[12]: last of the month",
+		"<165>Jan  1 00:00:00 10.0.0.1 cron: x",
+		"<34>Foo 11 22:14:15 mymachine su: x",
+		"no priority",
+	}
+
+	type outcome struct {
+		res *rfc3164.ResultRFC3164[any, any]
+		err string
+	}
+
+	parse := func(line string) outcome {
+		res, err := p.ParseBytes([]byte(line))
+		return outcome{res, fmt.Sprint(err)}
+	}
+
+	want := make([]outcome, len(lines))
+	for i, line := range lines {
+		want[i] = parse(line)
+	}
+
+	var wg sync.WaitGroup
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for n := 0; n < 200; n++ {
+				i := (g + n) % len(lines)
+
+				if got := parse(lines[i]); !reflect.DeepEqual(got, want[i]) {
+					t.Errorf("%q: got %+v, want %+v", lines[i], got, want[i])
+					return
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+}
+
+func TestDumpAfterFailedParse(t *testing.T) {
+	p := rfc3164.NewParser[any, any]()
+
+	if err := p.Parse(validMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Parse("<34>Foo 11 22:14:15 mymachine su: x"); err == nil {
+		t.Fatal("no error")
+	}
+
+	// nothing is left of the previous message
+	if res := p.Dump(); res == nil || res.Hostname != "" || res.OriginContent != "" {
+		t.Errorf("got %+v, want an empty result", res)
+	}
+}
+
+func TestWithAfterParse(t *testing.T) {
+	for name, parse := range map[string]func(p *rfc3164.Parser[any, any]){
+		"ParseBytes": func(p *rfc3164.Parser[any, any]) { p.ParseBytes([]byte(validMessage)) },
+		"ParseView":  func(p *rfc3164.Parser[any, any]) { p.ParseView([]byte(validMessage), &rfc3164.View{}) },
+		"Parse":      func(p *rfc3164.Parser[any, any]) { p.Parse("no priority") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := rfc3164.NewParser[any, any]()
+			p.WithLocation("UTC")
+			parse(p)
+
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, common.ErrParserInUse) {
+					t.Errorf("got %v, want a panic with %v", err, common.ErrParserInUse)
+				}
+			}()
+
+			p.WithMode(common.ModeStrict)
+		})
+	}
+}
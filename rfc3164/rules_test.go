@@ -0,0 +1,103 @@
+package rfc3164_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"strings"
+	"testing"
+)
+
+const validMessage = "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8"
+
+// ruleCases break one rule each, inDefault tells whether ModeDefault
+// rejects them too.
+var ruleCases = []struct {
+	name      string
+	rule      common.Rules
+	line      string
+	err       error
+	inDefault bool
+}{
+	{
+		name:      "PRIVAL beyond 191",
+		rule:      common.RulePriority,
+		line:      "<999>Oct 11 22:14:15 mymachine su: hi",
+		err:       common.ErrPriorityBeyondNumber,
+		inDefault: true,
+	},
+	{
+		name:      "missing PRI",
+		rule:      common.RulePriority,
+		line:      "Oct 11 22:14:15 mymachine su: hi",
+		err:       common.ErrPriorityNoStart,
+		inDefault: true,
+	},
+	{
+		name: "extra space",
+		rule: common.RuleSingleSpace,
+		line: "<34>Oct 11 22:14:15  mymachine su: hi",
+		err:  common.ErrExtraSpace,
+	},
+	{
+		name: "TAG too long",
+		rule: common.RuleFieldLength,
+		line: "<34>Oct 11 22:14:15 mymachine " + strings.Repeat("a", 33) + ": hi",
+		err:  common.ErrTagTooLong,
+	},
+	{
+		name: "HOSTNAME outside PRINTUSASCII",
+		rule: common.RulePrintUSASCII,
+		line: "<34>Oct 11 22:14:15 m\xc3\xa9chine su: hi",
+		err:  common.ErrNotPrintUSASCII,
+	},
+	{
+		name: "PRIVAL with leading zero",
+		rule: common.RuleCanonicalPriority,
+		line: "<034>Oct 11 22:14:15 mymachine su: hi",
+		err:  common.ErrPriorityLeadingZero,
+	},
+}
+
+func parseWithRules(rules common.Rules, line string) error {
+	p := rfc3164.NewParser[any, any]()
+	p.WithRules(rules)
+
+	_, err := p.ParseBytes([]byte(line))
+	return err
+}
+
+func TestModesAcceptValidMessage(t *testing.T) {
+	for _, mode := range []common.Mode{common.ModeDefault, common.ModeStrict, common.ModeLenient} {
+		if err := parseWithRules(mode.Rules(), validMessage); err != nil {
+			t.Errorf("%s: %v", mode, err)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	for _, tt := range ruleCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := parseWithRules(common.StrictRules, tt.line); !errors.Is(err, tt.err) {
+				t.Errorf("strict: got %v, want %v", err, tt.err)
+			}
+
+			if err := parseWithRules(common.StrictRules&^tt.rule, tt.line); err != nil {
+				t.Errorf("strict without the rule: %v", err)
+			}
+
+			err := parseWithRules(common.DefaultRules, tt.line)
+			if tt.inDefault && !errors.Is(err, tt.err) {
+				t.Errorf("default: got %v, want %v", err, tt.err)
+			}
+
+			if !tt.inDefault && err != nil {
+				t.Errorf("default: %v", err)
+			}
+
+			if err := parseWithRules(common.LenientRules, tt.line); err != nil {
+				t.Errorf("lenient: %v", err)
+			}
+		})
+	}
+}
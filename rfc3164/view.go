@@ -0,0 +1,192 @@
+package rfc3164
+
+import (
+	"bytes"
+	"github.com/deadspacewii/psyslog/common"
+	"math"
+	"time"
+)
+
+// View is an RFC 3164 message whose fields are views into the parsed
+// buffer, no string is allocated until the caller converts a field. A View
+// is only valid as long as the buffer is left unchanged and can be reused
+// for every message.
+type View struct {
+	Priority         int
+	Facility         common.Facility
+	Severity         common.Severity
+	PriorityInferred bool
+	Timestamp        []byte
+	Hostname         []byte
+	Tag              []byte
+	AppName          []byte
+	ProcId           []byte
+	Content          []byte
+	layout           string
+	location         *time.Location
+	years            *yearInference
+	timestamp        int
+	header           int
+	l                int
+}
+
+// ParseView parses b into v without allocating, the TIMESTAMP is only
+// delimited and Time decodes it on demand. Timestamp is nil when the
+// lenient fallback was taken. It is safe for concurrent use with distinct
+// views. On error v holds the fields parsed before the failure,
+// WithBestEffort also keeps the remainder as Content.
+func (p *Parser[T, D]) ParseView(b []byte, v *View) error {
+	p.freeze()
+	*v = View{}
+
+	c := cursor{
+		buff: b,
+		l:    int(math.Min(MAXPACKETLEN, float64(len(b)))),
+	}
+
+	v.l = c.l
+
+	if err := p.parsePriority(&c, v); err != nil {
+		if p.bestEffort {
+			c.index = 0
+			p.fallback(&c, v)
+		}
+
+		return err
+	}
+
+	v.header = c.index
+
+	if err := p.parseHeader(&c, v); err != nil {
+		if p.lenient {
+			c.index = v.header
+			p.fallback(&c, v)
+
+			return nil
+		}
+
+		if p.bestEffort {
+			v.Content = bytes.Trim(c.buff[c.field:c.l], " ")
+		}
+
+		return err
+	}
+
+	from := c.index
+
+	if err := p.parsemessage(&c, v); err != nil {
+		if p.bestEffort {
+			v.Content = bytes.Trim(c.buff[from:c.l], " ")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Time decodes the TIMESTAMP with the location of the parser, a TIMESTAMP
+// without year gets the one inferred by the parser, see WithYear. A nil
+// Timestamp yields the zero time.
+func (v *View) Time() (time.Time, error) {
+	if v.Timestamp == nil {
+		return time.Time{}, nil
+	}
+
+	ts, err := parseTimestamp(v.Timestamp, v.layout, v.location, v.years)
+	if err != nil {
+		e := common.NewParseError(v.Timestamp, 0, "TIMESTAMP", layoutExpected(v.layout), err)
+		e.Offset = v.timestamp
+
+		return ts, e
+	}
+
+	return ts, nil
+}
+
+// timestampExpected describes the accepted TIMESTAMP layouts.
+func timestampExpected(custom string) string {
+	if custom != "" {
+		return custom
+	}
+
+	return "Mmm dd hh:mm:ss"
+}
+
+// layoutExpected describes a layout returned by matchTimestamp the way
+// timestampExpected does.
+func layoutExpected(layout string) string {
+	switch layout {
+	case "Jan 02 15:04:05", "Jan _2 15:04:05", "Jan 02 2006 15:04:05":
+		return timestampExpected("")
+	}
+
+	return timestampExpected(layout)
+}
+
+var months = [12]string{
+	"jan", "feb", "mar", "apr", "may", "jun",
+	"jul", "aug", "sep", "oct", "nov", "dec",
+}
+
+// matchTimestamp returns the layout matching the start of b and its length,
+// or a zero length. A custom layout is trusted to have a fixed width.
+func matchTimestamp(b []byte, custom string) (string, int) {
+	if custom != "" {
+		if len(b) < len(custom) {
+			return "", 0
+		}
+
+		return custom, len(custom)
+	}
+
+	// Jan 02 15:04:05, Jan _2 15:04:05, Jan 02 2006 15:04:05
+	if len(b) < 15 || !isMonth(b[:3]) || b[3] != ' ' || b[6] != ' ' {
+		return "", 0
+	}
+
+	day := b[4:6]
+	layout := "Jan 02 15:04:05"
+
+	switch {
+	case day[0] == ' ' && isDigits(day[1:]):
+		layout = "Jan _2 15:04:05"
+	case !isDigits(day):
+		return "", 0
+	}
+
+	if isClock(b[7:15]) {
+		return layout, 15
+	}
+
+	if len(b) >= 20 && day[0] != ' ' && isDigits(b[7:11]) && b[11] == ' ' && isClock(b[12:20]) {
+		return "Jan 02 2006 15:04:05", 20
+	}
+
+	return "", 0
+}
+
+func isMonth(b []byte) bool {
+	for _, month := range months {
+		if b[0]|0x20 == month[0] && b[1]|0x20 == month[1] && b[2]|0x20 == month[2] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isClock matches 15:04:05, ranges are left to time.Parse
+func isClock(b []byte) bool {
+	return isDigits(b[0:2]) && b[2] == ':' && isDigits(b[3:5]) && b[5] == ':' && isDigits(b[6:8])
+}
+
+func isDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,40 @@
+package rfc3164_test
+
+import (
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"testing"
+)
+
+var viewMessage = []byte(`<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8`)
+
+func TestParseViewAllocs(t *testing.T) {
+	p := rfc3164.NewParser[any, any]()
+
+	var v rfc3164.View
+
+	if err := p.ParseView(viewMessage, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		p.ParseView(viewMessage, &v)
+	})
+
+	if allocs > 0 {
+		t.Errorf("ParseView allocates %.0f times per message, want 0", allocs)
+	}
+}
+
+func BenchmarkParseView(b *testing.B) {
+	p := rfc3164.NewParser[any, any]()
+
+	var v rfc3164.View
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := p.ParseView(viewMessage, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
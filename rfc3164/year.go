@@ -0,0 +1,70 @@
+package rfc3164
+
+import (
+	"time"
+)
+
+// YEARTOLERANCE is how far after the reference time an inferred TIMESTAMP may
+// lie, it absorbs the clock skew between sender and receiver.
+const YEARTOLERANCE = 24 * time.Hour
+
+// yearInference completes a TIMESTAMP without year, the year is the one
+// given to WithYear or else the latest one which does not put the TIMESTAMP
+// after the reference time, which defaults to the clock.
+type yearInference struct {
+	year      int
+	reference time.Time
+	clock     func() time.Time
+}
+
+var defaultYearInference yearInference
+
+func (y *yearInference) now() time.Time {
+	if y.clock != nil {
+		return y.clock()
+	}
+
+	return time.Now()
+}
+
+// complete returns ts in the inferred year, a ts carrying a year is left
+// unchanged.
+func (y *yearInference) complete(ts time.Time) time.Time {
+	if y == nil {
+		y = &defaultYearInference
+	}
+
+	if ts.Year() != 0 {
+		return ts
+	}
+
+	if y.year != 0 {
+		return inYear(ts, y.year)
+	}
+
+	ref := y.reference
+	if ref.IsZero() {
+		ref = y.now()
+	}
+
+	// a message is not dated after it was received, Dec 31 read on Jan 1
+	// belongs to the year before and Feb 29 to the last leap year
+	limit := ref.Add(YEARTOLERANCE)
+
+	for year := ref.Year() + 1; year > ref.Year()-8; year-- {
+		candidate := inYear(ts, year)
+		if candidate.Day() == ts.Day() && !candidate.After(limit) {
+			return candidate
+		}
+	}
+
+	return inYear(ts, ref.Year())
+}
+
+func inYear(ts time.Time, year int) time.Time {
+	return time.Date(
+		year, ts.Month(), ts.Day(),
+		ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(),
+		ts.Location(),
+	)
+}
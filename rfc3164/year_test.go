@@ -0,0 +1,141 @@
+package rfc3164_test
+
+import (
+	"github.com/deadspacewii/psyslog/rfc3164"
+	"testing"
+	"time"
+)
+
+func fixedClock(s string) func() time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+
+	return func() time.Time {
+		return t
+	}
+}
+
+func TestYearInference(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		clock string
+		want  string
+	}{
+		{
+			name:  "same day",
+			line:  "<34>Oct 17 09:00:00 mymachine su: x",
+			clock: "2026-10-17T10:00:00Z",
+			want:  "2026-10-17T09:00:00Z",
+		},
+		{
+			name:  "Dec 31 received on Jan 1",
+			line:  "<34>Dec 31 23:59:59 mymachine su: x",
+			clock: "2027-01-01T00:00:05Z",
+			want:  "2026-12-31T23:59:59Z",
+		},
+		{
+			name:  "Jan 1 from a sender ahead of the clock",
+			line:  "<34>Jan  1 00:00:05 mymachine su: x",
+			clock: "2026-12-31T23:59:59Z",
+			want:  "2027-01-01T00:00:05Z",
+		},
+		{
+			name:  "six months old",
+			line:  "<34>Apr 17 12:00:00 mymachine su: x",
+			clock: "2026-10-17T00:00:00Z",
+			want:  "2026-04-17T12:00:00Z",
+		},
+		{
+			name:  "eight months old",
+			line:  "<34>Feb 05 17:32:18 mymachine su: x",
+			clock: "2026-10-17T00:00:00Z",
+			want:  "2026-02-05T17:32:18Z",
+		},
+		{
+			name:  "later this year is last year",
+			line:  "<34>Nov 20 08:00:00 mymachine su: x",
+			clock: "2026-10-17T00:00:00Z",
+			want:  "2025-11-20T08:00:00Z",
+		},
+		{
+			name:  "Feb 29 in a leap year",
+			line:  "<34>Feb 29 12:00:00 mymachine su: x",
+			clock: "2024-03-10T00:00:00Z",
+			want:  "2024-02-29T12:00:00Z",
+		},
+		{
+			name:  "Feb 29 is the last leap year",
+			line:  "<34>Feb 29 12:00:00 mymachine su: x",
+			clock: "2027-01-10T00:00:00Z",
+			want:  "2024-02-29T12:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := rfc3164.NewParser[any, any]()
+			p.WithLocation("UTC")
+			p.WithClock(fixedClock(tt.clock))
+
+			res, err := p.ParseBytes([]byte(tt.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := res.Timestamp.Format(time.RFC3339); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearReferenceTime(t *testing.T) {
+	p := rfc3164.NewParser[any, any]()
+	p.WithLocation("UTC")
+	p.WithClock(fixedClock("2026-10-17T00:00:00Z"))
+	p.WithReferenceTime(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	res, err := p.ParseBytes([]byte("<34>Dec 31 23:59:59 mymachine su: x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := res.Timestamp.Format(time.RFC3339), "2019-12-31T23:59:59Z"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestYearExplicit(t *testing.T) {
+	p := rfc3164.NewParser[any, any]()
+	p.WithLocation("UTC")
+	p.WithClock(fixedClock("2026-10-17T00:00:00Z"))
+	p.WithYear(2019)
+
+	res, err := p.ParseBytes([]byte("<34>Nov 20 08:00:00 mymachine su: x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := res.Timestamp.Format(time.RFC3339), "2019-11-20T08:00:00Z"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestReceptionTimeUsesClock(t *testing.T) {
+	p := rfc3164.NewParser[any, any]()
+	p.WithLocation("UTC")
+	p.WithLenient()
+	p.WithClock(fixedClock("2001-01-01T00:00:00Z"))
+
+	res, err := p.ParseBytes([]byte("<34>no header at all"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := res.Timestamp.Format(time.RFC3339), "2001-01-01T00:00:00Z"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
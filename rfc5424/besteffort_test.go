@@ -0,0 +1,61 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBestEffortKeepsParsedFields(t *testing.T) {
+	stamp := time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+
+	tests := []struct {
+		name     string
+		line     string
+		err      error
+		hostname string
+		message  string
+	}{
+		{
+			name:    "extra space before HOSTNAME",
+			line:    "<165>1 2003-10-11T22:14:15.003Z  host app - ID47 - hi",
+			err:     common.ErrExtraSpace,
+			message: "host app - ID47 - hi",
+		},
+		{
+			name:     "APP-NAME too long",
+			line:     "<165>1 2003-10-11T22:14:15.003Z host " + strings.Repeat("a", 49) + " - ID47 - hi",
+			err:      rfc5424.ErrInvalidAppName,
+			hostname: "host",
+			message:  strings.Repeat("a", 49) + " - ID47 - hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := rfc5424.NewParser[any]()
+			p.WithMode(common.ModeStrict)
+			p.WithBestEffort()
+
+			res, err := p.ParseBytes([]byte(tt.line))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+
+			if !res.Timestamp.Equal(stamp) {
+				t.Errorf("timestamp: got %v, want %v", res.Timestamp, stamp)
+			}
+
+			if res.Hostname != tt.hostname {
+				t.Errorf("hostname: got %q, want %q", res.Hostname, tt.hostname)
+			}
+
+			if res.Message != tt.message {
+				t.Errorf("message: got %q, want %q", res.Message, tt.message)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ type Builder struct {
 	procId         string
 	msgId          string
 	structuredData string
+	sdElements     []SDElement
 	message        string
 	result         string
 }
@@ -25,11 +26,40 @@ func NewBuilder() *Builder {
 	}
 }
 
+// NewBuilderFromMessage returns a builder preset with the fields of m, the
+// VERSION is 1.
+func NewBuilderFromMessage(m *common.Message) *Builder {
+	b := NewBuilder().
+		SetPriority(m.Priority).
+		SetVersion(1).
+		SetAppName(m.AppName).
+		SetProcId(m.ProcID).
+		SetMsgId(m.MsgID).
+		AddSDElements(m.StructuredData...).
+		SetMessage(m.Message)
+
+	if m.Hostname != "" {
+		b.SetHostName(m.Hostname)
+	}
+
+	if !m.Timestamp.IsZero() {
+		b.SetTimestamp(m.Timestamp.Format(TIMESTAMPFORMAT))
+	}
+
+	return b
+}
+
 func (b *Builder) SetPriority(priority int) *Builder {
 	b.priority = priority
 	return b
 }
 
+// SetFacilitySeverity sets the PRI of facility and severity.
+func (b *Builder) SetFacilitySeverity(facility common.Facility, severity common.Severity) *Builder {
+	b.priority = common.PriorityOf(facility, severity)
+	return b
+}
+
 func (b *Builder) SetVersion(version int) *Builder {
 	b.version = version
 	return b
@@ -60,11 +90,46 @@ func (b *Builder) SetMsgId(msgId string) *Builder {
 	return b
 }
 
+// SetStructuredData sets a pre-formatted element body, it is wrapped in
+// brackets and rendered before the elements added by AddSDElement.
 func (b *Builder) SetStructuredData(structuredData string) *Builder {
 	b.structuredData = strings.TrimSpace(structuredData)
 	return b
 }
 
+// AddSDElement appends a new SD element, elements are rendered in the
+// order they were added.
+func (b *Builder) AddSDElement(id string, params ...SDParam) *Builder {
+	b.sdElements = append(b.sdElements, SDElement{
+		ID:     id,
+		Params: append([]SDParam(nil), params...),
+	})
+	return b
+}
+
+// AddSDElements appends elements, typically the output of Marshal.
+func (b *Builder) AddSDElements(elements ...SDElement) *Builder {
+	for _, element := range elements {
+		b.AddSDElement(element.ID, element.Params...)
+	}
+	return b
+}
+
+// AddSDParam appends a param to the element identified by id, the element
+// is created when it does not exist yet.
+func (b *Builder) AddSDParam(id, name, value string) *Builder {
+	param := SDParam{Name: name, Value: value}
+
+	for i := range b.sdElements {
+		if b.sdElements[i].ID == id {
+			b.sdElements[i].Params = append(b.sdElements[i].Params, param)
+			return b
+		}
+	}
+
+	return b.AddSDElement(id, param)
+}
+
 func (b *Builder) SetMessage(message string) *Builder {
 	b.message = strings.TrimSpace(message)
 	return b
@@ -87,6 +152,10 @@ func (b *Builder) check() error {
 		return err
 	}
 
+	if err := checkSDElements(b.sdElements); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -95,10 +164,14 @@ func checkVersion(version int) error {
 		return common.ErrVersionNotFound
 	}
 
-	return nil
+	return common.DefaultSupportedVersions.Check(version)
 }
 
 func checkTimestamp(timestamp string) error {
+	if timestamp == "" {
+		return nil
+	}
+
 	buff := []byte(timestamp)
 	l := len(buff)
 	index := 0
@@ -153,12 +226,16 @@ func (b *Builder) Build() error {
 		msgId = b.msgId
 	}
 
-	if b.structuredData == "" {
-		data = string(NILVALUE)
-	} else {
+	if b.structuredData != "" {
 		data = fmt.Sprintf("[%s]", b.structuredData)
 	}
 
+	data += formatSDElements(b.sdElements)
+
+	if data == "" {
+		data = string(NILVALUE)
+	}
+
 	log := fmt.Sprintf(RFC5424FORMAT, b.priority, b.version, ts, b.hostName, appName, procId, msgId, data)
 
 	if b.message != "" {
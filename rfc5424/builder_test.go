@@ -0,0 +1,128 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const builderHeader = "<165>1 - - - - - "
+
+func newBuilder() *rfc5424.Builder {
+	return rfc5424.NewBuilder().SetPriority(165).SetVersion(1)
+}
+
+func TestBuilderStructuredData(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *rfc5424.Builder)
+		want  string
+	}{
+		{
+			name:  "no elements",
+			build: func(b *rfc5424.Builder) {},
+			want:  "-",
+		},
+		{
+			name: "escaped values",
+			build: func(b *rfc5424.Builder) {
+				b.AddSDElement("a", rfc5424.SDParam{Name: "q", Value: `say "hi"`}).
+					AddSDParam("a", "b", `C:\tmp`).
+					AddSDParam("a", "c", "[x]")
+			},
+			want: `[a q="say \"hi\"" b="C:\\tmp" c="[x\]"]`,
+		},
+		{
+			name: "element order",
+			build: func(b *rfc5424.Builder) {
+				b.AddSDElement("c").
+					AddSDParam("a", "x", "1").
+					AddSDElement("b", rfc5424.SDParam{Name: "y", Value: "2"}).
+					AddSDParam("a", "z", "3").
+					AddSDParam("c", "w", "4")
+			},
+			want: `[c w="4"][a x="1" z="3"][b y="2"]`,
+		},
+		{
+			name: "private and registered SD-IDs",
+			build: func(b *rfc5424.Builder) {
+				b.AddSDElement("timeQuality", rfc5424.SDParam{Name: "tzKnown", Value: "1"}).
+					AddSDParam("exampleSDID@32473", "iut", "3")
+			},
+			want: `[timeQuality tzKnown="1"][exampleSDID@32473 iut="3"]`,
+		},
+		{
+			name: "pre-formatted body first",
+			build: func(b *rfc5424.Builder) {
+				b.AddSDElement("b").SetStructuredData(`a x="1"`)
+			},
+			want: `[a x="1"][b]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBuilder()
+			tt.build(b)
+
+			if err := b.Build(); err != nil {
+				t.Fatal(err)
+			}
+
+			if want := builderHeader + tt.want; b.String() != want {
+				t.Fatalf("got %s, want %s", b.String(), want)
+			}
+
+			// the elements parse back to what was added
+			res, err := rfc5424.NewParser[any]().ParseBytes([]byte(b.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := rfc5424.ParseSDElements(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(res.SDElements, want) {
+				t.Errorf("got %+v, want %+v", res.SDElements, want)
+			}
+		})
+	}
+}
+
+func TestBuilderStructuredDataErrors(t *testing.T) {
+	name32 := strings.Repeat("n", 32)
+
+	tests := []struct {
+		name  string
+		build func(b *rfc5424.Builder)
+		err   error
+	}{
+		{"32 chars SD-ID", func(b *rfc5424.Builder) { b.AddSDElement(name32) }, nil},
+		{"32 chars PARAM-NAME", func(b *rfc5424.Builder) { b.AddSDParam("a", name32, "v") }, nil},
+		{"SD-ID too long", func(b *rfc5424.Builder) { b.AddSDElement(name32 + "n") }, rfc5424.ErrSDIDTooLong},
+		{"PARAM-NAME too long", func(b *rfc5424.Builder) { b.AddSDParam("a", name32+"n", "v") }, rfc5424.ErrParamNameTooLong},
+		{"empty SD-ID", func(b *rfc5424.Builder) { b.AddSDElement("") }, rfc5424.ErrSDIDEmpty},
+		{"empty PARAM-NAME", func(b *rfc5424.Builder) { b.AddSDParam("a", "", "v") }, rfc5424.ErrParamNameEmpty},
+		{"space in SD-ID", func(b *rfc5424.Builder) { b.AddSDElement("a b") }, rfc5424.ErrSDIDInvalid},
+		{"bracket in SD-ID", func(b *rfc5424.Builder) { b.AddSDElement("a]") }, rfc5424.ErrSDIDInvalid},
+		{"equal sign in PARAM-NAME", func(b *rfc5424.Builder) { b.AddSDParam("a", "x=y", "v") }, rfc5424.ErrParamNameInvalid},
+		{"quote in PARAM-NAME", func(b *rfc5424.Builder) { b.AddSDParam("a", `x"`, "v") }, rfc5424.ErrParamNameInvalid},
+		{"non ASCII PARAM-NAME", func(b *rfc5424.Builder) { b.AddSDParam("a", "é", "v") }, rfc5424.ErrParamNameInvalid},
+		{"duplicate SD-ID", func(b *rfc5424.Builder) { b.AddSDElement("a").AddSDElement("a") }, rfc5424.ErrSDIDDuplicate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBuilder()
+			tt.build(b)
+
+			if err := b.Build(); !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
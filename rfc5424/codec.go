@@ -0,0 +1,194 @@
+package rfc5424
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deadspacewii/psyslog/common"
+	"reflect"
+)
+
+var (
+	ErrUnknownParam = errors.New("Unknown SD param")
+	ErrMissingParam = errors.New("Missing SD param")
+)
+
+// ParamError reports the SD param a codec failed on.
+type ParamError struct {
+	ID   string
+	Name string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s: %s,%s", e.Err.Error(), e.ID, e.Name)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// Decoder fills tagged struct fields from SD elements, a field tagged
+// `syslog:"origin@32473,ip"` receives the value of the ip param of the
+// origin@32473 element. Slice fields collect repeated params.
+type Decoder struct {
+	allowUnknown bool
+	allowMissing bool
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// WithAllowUnknownParams stops reporting params of a decoded SD-ID that no
+// field is tagged with.
+func (d *Decoder) WithAllowUnknownParams() *Decoder {
+	d.allowUnknown = true
+	return d
+}
+
+// WithAllowMissingParams stops reporting tagged fields whose param is absent,
+// the same as tagging every field with omitempty.
+func (d *Decoder) WithAllowMissingParams() *Decoder {
+	d.allowMissing = true
+	return d
+}
+
+// Decode stores elements into the struct pointed to by v. Like
+// encoding/json it decodes every field it can and returns the first error.
+func (d *Decoder) Decode(elements []SDElement, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return common.ErrCodecNotPointer
+	}
+
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return common.ErrCodecNotPointer
+	}
+
+	var firstErr error
+
+	report := func(id, name string, err error) {
+		if firstErr == nil {
+			firstErr = &ParamError{ID: id, Name: name, Err: err}
+		}
+	}
+
+	type key struct{ id, name string }
+
+	values := make(map[key][]string)
+	for _, element := range elements {
+		for _, param := range element.Params {
+			k := key{element.ID, param.Name}
+			values[k] = append(values[k], param.Value)
+		}
+	}
+
+	known := make(map[key]struct{})
+	ids := make(map[string]struct{})
+
+	for _, field := range common.StructFields(rv.Type()) {
+		k := key{field.Tag.ID, field.Tag.Name}
+		known[k] = struct{}{}
+		ids[k.id] = struct{}{}
+
+		if k.id == "" {
+			report(k.id, k.name, ErrSDIDEmpty)
+			continue
+		}
+
+		vs, ok := values[k]
+		if !ok {
+			if !field.Tag.OmitEmpty && !d.allowMissing {
+				report(k.id, k.name, ErrMissingParam)
+			}
+			continue
+		}
+
+		if err := common.SetValue(rv.FieldByIndex(field.Index), vs); err != nil {
+			report(k.id, k.name, err)
+		}
+	}
+
+	if !d.allowUnknown {
+		for _, element := range elements {
+			if _, ok := ids[element.ID]; !ok {
+				continue
+			}
+
+			for _, param := range element.Params {
+				if _, ok := known[key{element.ID, param.Name}]; !ok {
+					report(element.ID, param.Name, ErrUnknownParam)
+				}
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Unmarshal decodes elements into v with a default Decoder.
+func Unmarshal(elements []SDElement, v any) error {
+	return NewDecoder().Decode(elements, v)
+}
+
+// Marshal encodes the tagged fields of the struct v into SD elements, the
+// inverse of Unmarshal. Elements are ordered by the first field tagged with
+// their SD-ID, slice fields yield one param per item and omitempty fields
+// are skipped when zero.
+func Marshal(v any) ([]SDElement, error) {
+	rv, err := common.StructValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []SDElement
+
+	positions := make(map[string]int)
+
+	for _, field := range common.StructFields(rv.Type()) {
+		id, name := field.Tag.ID, field.Tag.Name
+
+		if id == "" {
+			return nil, &ParamError{ID: id, Name: name, Err: ErrSDIDEmpty}
+		}
+
+		values, err := common.FormatValue(rv.FieldByIndex(field.Index), field.Tag.OmitEmpty)
+		if err != nil {
+			return nil, &ParamError{ID: id, Name: name, Err: err}
+		}
+
+		pos, ok := positions[id]
+		if !ok {
+			pos = len(elements)
+			positions[id] = pos
+			elements = append(elements, SDElement{ID: id})
+		}
+
+		for _, value := range values {
+			elements[pos].Params = append(elements[pos].Params, SDParam{Name: name, Value: value})
+		}
+	}
+
+	return elements, nil
+}
+
+// UnmarshalStructuredData is a StructureFunc decoding the origin
+// structured data with Unmarshal, NewParser installs it when D has
+// `syslog` tagged fields.
+func UnmarshalStructuredData[D any](s string) (D, error) {
+	var d D
+
+	elements, err := ParseSDElements(s)
+	if err != nil {
+		return d, err
+	}
+
+	rv := reflect.ValueOf(&d).Elem()
+	if rv.Kind() == reflect.Pointer {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		return d, Unmarshal(elements, rv.Interface())
+	}
+
+	return d, Unmarshal(elements, &d)
+}
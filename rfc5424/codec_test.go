@@ -0,0 +1,273 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type origin struct {
+	Software string        `syslog:"origin@32473,software"`
+	Count    int           `syslog:"origin@32473,count"`
+	Enabled  bool          `syslog:"origin@32473,enabled"`
+	Time     time.Time     `syslog:"origin@32473,time"`
+	IP       net.IP        `syslog:"origin@32473,ip"`
+	Zones    []int         `syslog:"origin@32473,zone,omitempty"`
+	Timeout  time.Duration `syslog:"meta,timeout,omitempty"`
+	Sequence *uint32       `syslog:"meta,sequenceId,omitempty"`
+}
+
+const originSD = `[origin@32473 software="beer" count="42" enabled="true" time="2003-10-11T22:14:15.003Z" ip="192.0.2.1" zone="1" zone="2"]` +
+	`[meta timeout="1m30s" sequenceId="7"]`
+
+func TestDecode(t *testing.T) {
+	elements, err := rfc5424.ParseSDElements(originSD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got origin
+	if err := rfc5424.Unmarshal(elements, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	sequence := uint32(7)
+	want := origin{
+		Software: "beer",
+		Count:    42,
+		Enabled:  true,
+		Time:     time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+		IP:       net.IPv4(192, 0, 2, 1),
+		Zones:    []int{1, 2},
+		Timeout:  90 * time.Second,
+		Sequence: &sequence,
+	}
+
+	if !got.Time.Equal(want.Time) || !got.IP.Equal(want.IP) {
+		t.Errorf("got %v %v, want %v %v", got.Time, got.IP, want.Time, want.IP)
+	}
+
+	got.Time, got.IP = want.Time, want.IP
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sd      string
+		decoder *rfc5424.Decoder
+		id      string
+		param   string
+		err     error
+	}{
+		{
+			name:    "unknown param",
+			sd:      `[origin@32473 software="a" count="1" enabled="1" time="2003-10-11T22:14:15Z" ip="::1" vendor="x"]`,
+			decoder: rfc5424.NewDecoder(),
+			id:      "origin@32473",
+			param:   "vendor",
+			err:     rfc5424.ErrUnknownParam,
+		},
+		{
+			name:    "missing param",
+			sd:      `[origin@32473 software="a" count="1" enabled="1" time="2003-10-11T22:14:15Z"]`,
+			decoder: rfc5424.NewDecoder(),
+			id:      "origin@32473",
+			param:   "ip",
+			err:     rfc5424.ErrMissingParam,
+		},
+		{
+			name:    "missing element",
+			sd:      `[meta timeout="1s"]`,
+			decoder: rfc5424.NewDecoder(),
+			id:      "origin@32473",
+			param:   "software",
+			err:     rfc5424.ErrMissingParam,
+		},
+		{
+			name:    "invalid int",
+			sd:      `[origin@32473 software="a" count="many" enabled="1" time="2003-10-11T22:14:15Z" ip="::1"]`,
+			decoder: rfc5424.NewDecoder(),
+			id:      "origin@32473",
+			param:   "count",
+			err:     strconv.ErrSyntax,
+		},
+		{
+			name:    "invalid slice item",
+			sd:      `[origin@32473 software="a" count="1" enabled="1" time="2003-10-11T22:14:15Z" ip="::1" zone="1" zone="b"]`,
+			decoder: rfc5424.NewDecoder(),
+			id:      "origin@32473",
+			param:   "zone",
+			err:     strconv.ErrSyntax,
+		},
+		{
+			name:    "repeated non slice param",
+			sd:      `[origin@32473 software="a" software="b" count="1" enabled="1" time="2003-10-11T22:14:15Z" ip="::1"]`,
+			decoder: rfc5424.NewDecoder(),
+			id:      "origin@32473",
+			param:   "software",
+			err:     common.ErrCodecMultipleValues,
+		},
+		{
+			name:    "unknown param allowed",
+			sd:      `[origin@32473 software="a" count="1" enabled="1" time="2003-10-11T22:14:15Z" ip="::1" vendor="x"]`,
+			decoder: rfc5424.NewDecoder().WithAllowUnknownParams(),
+		},
+		{
+			name:    "missing param allowed",
+			sd:      `[origin@32473 software="a"]`,
+			decoder: rfc5424.NewDecoder().WithAllowMissingParams(),
+		},
+		{
+			// only params of the SD-IDs the struct is tagged with are checked
+			name:    "untagged element",
+			sd:      `[origin@32473 software="a" count="1" enabled="1" time="2003-10-11T22:14:15Z" ip="::1"][timeQuality tzKnown="1"]`,
+			decoder: rfc5424.NewDecoder(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elements, err := rfc5424.ParseSDElements(tt.sd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var o origin
+			err = tt.decoder.Decode(elements, &o)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+
+			if tt.err == nil {
+				if o.Software != "a" {
+					t.Errorf("got software %q, want a", o.Software)
+				}
+				return
+			}
+
+			var pe *rfc5424.ParamError
+			if !errors.As(err, &pe) || pe.ID != tt.id || pe.Name != tt.param {
+				t.Errorf("got %v, want a ParamError for %s,%s", err, tt.id, tt.param)
+			}
+		})
+	}
+}
+
+func TestDecodeTarget(t *testing.T) {
+	var o origin
+
+	for _, v := range []any{o, (*origin)(nil), new(int), nil} {
+		if err := rfc5424.Unmarshal(nil, v); !errors.Is(err, common.ErrCodecNotPointer) {
+			t.Errorf("%T: got %v, want %v", v, err, common.ErrCodecNotPointer)
+		}
+	}
+}
+
+// NewParser decodes the structured data of a D with tagged fields
+func TestParserDecodesStructuredData(t *testing.T) {
+	line := []byte(sdHeader + originSD + " msg")
+
+	res, err := rfc5424.NewParser[origin]().ParseBytes(line)
+	if err != nil || res.StructuredErr != nil {
+		t.Fatal(err, res.StructuredErr)
+	}
+
+	if res.StructuredData.Software != "beer" || !reflect.DeepEqual(res.StructuredData.Zones, []int{1, 2}) {
+		t.Errorf("got %+v", res.StructuredData)
+	}
+
+	ptr, err := rfc5424.NewParser[*origin]().ParseBytes(line)
+	if err != nil || ptr.StructuredErr != nil {
+		t.Fatal(err, ptr.StructuredErr)
+	}
+
+	if ptr.StructuredData == nil || ptr.StructuredData.Count != 42 {
+		t.Errorf("got %+v", ptr.StructuredData)
+	}
+
+	// a decoding error is kept apart, the message itself parsed fine
+	missing, err := rfc5424.NewParser[origin]().ParseBytes([]byte(sdHeader + `[origin@32473 software="beer"] msg`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(missing.StructuredErr, rfc5424.ErrMissingParam) {
+		t.Errorf("got %v, want %v", missing.StructuredErr, rfc5424.ErrMissingParam)
+	}
+
+	// without tags the structured data is left alone
+	untagged, err := rfc5424.NewParser[struct{ Software string }]().ParseBytes(line)
+	if err != nil || untagged.StructuredErr != nil || untagged.StructuredData.Software != "" {
+		t.Errorf("got %+v, %v", untagged, err)
+	}
+}
+
+// a struct encoded by Marshal and built into a message decodes back to the
+// same values
+func TestMarshalRoundTrip(t *testing.T) {
+	sequence := uint32(7)
+	want := origin{
+		Software: `a "quoted" [value] \ with escapes`,
+		Count:    -3,
+		Enabled:  true,
+		Time:     time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+		IP:       net.ParseIP("2001:db8::1"),
+		Zones:    []int{1, 2, 3},
+		Timeout:  1500 * time.Millisecond,
+		Sequence: &sequence,
+	}
+
+	elements, err := rfc5424.Marshal(&want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := rfc5424.NewBuilder().
+		SetPriority(165).
+		SetVersion(1).
+		SetAppName("evntslog").
+		AddSDElements(elements...).
+		SetMessage("msg")
+
+	if err := b.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := rfc5424.NewParser[origin]().ParseBytes([]byte(b.String()))
+	if err != nil || res.StructuredErr != nil {
+		t.Fatal(b.String(), err, res.StructuredErr)
+	}
+
+	if !reflect.DeepEqual(res.SDElements, elements) {
+		t.Errorf("got %+v, want %+v", res.SDElements, elements)
+	}
+
+	got := res.StructuredData
+	if !got.Time.Equal(want.Time) || !got.IP.Equal(want.IP) {
+		t.Errorf("got %v %v, want %v %v", got.Time, got.IP, want.Time, want.IP)
+	}
+
+	got.Time, got.IP = want.Time, want.IP
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// omitempty fields are left out, the element they are tagged with stays
+	elements, err = rfc5424.Marshal(origin{Software: "a", IP: net.IPv6loopback})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(elements) != 2 || len(elements[0].Params) != 5 || elements[1].ID != "meta" || len(elements[1].Params) != 0 {
+		t.Errorf("got %+v, want the five origin@32473 params and an empty meta", elements)
+	}
+}
@@ -9,6 +9,10 @@ const (
 	MAXPACKETLEN              = 5120
 	SUBSIDIARYTIMEFORMAT      = "2006-01-02 15:04:05-07:00"
 	SUBSIDIARYTIMECHILDFORMAT = "2006-01-02 15:04:05"
+
+	// TIMESTAMPFORMAT is RFC 3339 limited to the 6 TIME-SECFRAC digits
+	// allowed by https://tools.ietf.org/html/rfc5424#section-6.2.3
+	TIMESTAMPFORMAT = "2006-01-02T15:04:05.999999Z07:00"
 )
 
 const (
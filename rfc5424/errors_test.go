@@ -0,0 +1,104 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		line     string
+		mode     common.Mode
+		err      error
+		field    string
+		offset   int
+		snippet  string
+		expected string
+	}{
+		{
+			line:     "165>1 2003-10-11T22:14:15.003Z h a - ID47 - x",
+			err:      common.ErrPriorityNoStart,
+			field:    "PRI",
+			offset:   0,
+			snippet:  "165>1 2003-10-11",
+			expected: `"<" PRIVAL ">"`,
+		},
+		{
+			line:     "<165>x 2003-10-11T22:14:15.003Z h a - ID47 - x",
+			err:      common.ErrVersionNotFound,
+			field:    "VERSION",
+			offset:   5,
+			snippet:  "x 2003-10-11T22:",
+			expected: "NONZERO-DIGIT 0*2DIGIT",
+		},
+		{
+			line:     "<165>1 2003-13-11T22:14:15.003Z h a - ID47 - x",
+			err:      rfc5424.ErrMonthInvalid,
+			field:    "TIMESTAMP",
+			offset:   12,
+			snippet:  "13-11T22:14:15.0",
+			expected: "DATE-MONTH",
+		},
+		{
+			line:     "<165>1 2003-10-11T22:14:15.003Z h",
+			err:      rfc5424.ErrInvalidHostname,
+			field:    "HOSTNAME",
+			offset:   33,
+			expected: "SP",
+		},
+		{
+			line:     "<165>1 2003-10-11T22:14:15.003Z h a - " + strings.Repeat("m", 33) + " - x",
+			mode:     common.ModeStrict,
+			err:      rfc5424.ErrInvalidMsgId,
+			field:    "MSGID",
+			offset:   70,
+			snippet:  "m - x",
+			expected: "SP",
+		},
+		{
+			line:     "<165>1 2003-10-11T22:14:15.003Z h a - ID47 x",
+			err:      rfc5424.ErrNoStructuredData,
+			field:    "STRUCTURED-DATA",
+			offset:   43,
+			snippet:  "x",
+			expected: "NILVALUE / 1*SD-ELEMENT",
+		},
+		{
+			line:     "<165>1 2003-10-11T22:14:15.003Z h a - ID47 [a x=y] x",
+			err:      rfc5424.ErrParamValueNoQuote,
+			field:    "STRUCTURED-DATA",
+			offset:   48,
+			snippet:  "y] x",
+			expected: "%d34",
+		},
+	}
+
+	for _, tt := range tests {
+		p := rfc5424.NewParser[any]()
+		p.WithMode(tt.mode)
+
+		_, err := p.ParseBytes([]byte(tt.line))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: got %v, want %v", tt.line, err, tt.err)
+			continue
+		}
+
+		var pe *common.ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: got %T, want a ParseError", tt.line, err)
+			continue
+		}
+
+		if pe.Field != tt.field || pe.Offset != tt.offset || pe.Snippet != tt.snippet || pe.Expected != tt.expected {
+			t.Errorf("%q: got %s at %d near %q expected %q, want %s at %d near %q expected %q",
+				tt.line, pe.Field, pe.Offset, pe.Snippet, pe.Expected, tt.field, tt.offset, tt.snippet, tt.expected)
+		}
+
+		if errors.Unwrap(err) != tt.err {
+			t.Errorf("%q: unwraps to %v, want %v", tt.line, errors.Unwrap(err), tt.err)
+		}
+	}
+}
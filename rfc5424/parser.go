@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"github.com/deadspacewii/psyslog/common"
 	"math"
+	"reflect"
 	"strconv"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 type StructureFunc[D any] func(string) (D, error)
@@ -17,7 +20,7 @@ var (
 	ErrMonthInvalid      = errors.New("Invalid month in timestamp")
 	ErrDayInvalid        = errors.New("Invalid day in timestamp")
 	ErrHourInvalid       = errors.New("Invalid hour in timestamp")
-	ErrMinuteInvalid     = errors.New("Invalid hour in timestamp")
+	ErrMinuteInvalid     = errors.New("Invalid minute in timestamp")
 	ErrSecondInvalid     = errors.New("Invalid second in timestamp")
 	ErrSecFracInvalid    = errors.New("Invalid fraction of second in timestamp")
 	ErrTimeZoneInvalid   = errors.New("Invalid time zone in timestamp")
@@ -26,42 +29,60 @@ var (
 	ErrInvalidProcId     = errors.New("Invalid proc ID")
 	ErrInvalidMsgId      = errors.New("Invalid msg ID")
 	ErrNoStructuredData  = errors.New("No structured data")
+	ErrInvalidHostname   = errors.New("Invalid hostname")
+	ErrInvalidUTF8       = errors.New("MSG after BOM is not valid UTF-8")
 )
 
+var (
+	nilValue = []byte{NILVALUE}
+	bom      = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// Parser holds the configuration of RFC 5424 parsing, the With methods
+// panic with common.ErrParserInUse once it parsed a message. ParseBytes
+// keeps its state on the stack so one configured Parser can serve many
+// goroutines.
 type Parser[D any] struct {
-	buff                     []byte
-	index                    int
-	l                        int
-	header                   *header
-	structuredData           string
-	message                  string
 	customStructuredDataFunc StructureFunc[D]
+	rules                    common.Rules
+	defaultPriority          int
+	inferPriority            bool
+	versions                 common.SupportedVersions
+	bestEffort               bool
+	result                   *ResultRFC5424[D]
+	used                     atomic.Bool
 }
 
 type ResultRFC5424[D any] struct {
-	Priority             int       `json:"priority"`
-	Facility             int       `json:"facility"`
-	Severity             int       `json:"severity"`
-	Version              int       `json:"version"`
-	Timestamp            time.Time `json:"timestamp"`
-	Hostname             string    `json:"hostname"`
-	AppName              string    `json:"app_name"`
-	ProcId               string    `json:"proc_id"`
-	MsgId                string    `json:"msg_id"`
-	Message              string    `json:"message"`
-	OriginStructuredData string    `json:"origin_structured_data"`
-	StructuredData       D         `json:"structured_data"`
-	StructuredErr        error     `json:"structured_err"`
-}
-
-type header struct {
-	priority  *common.Priority
-	version   int
-	timestamp time.Time
-	hostname  string
-	appName   string
-	procId    string
-	msgId     string
+	Priority             int             `json:"priority"`
+	Facility             common.Facility `json:"facility"`
+	Severity             common.Severity `json:"severity"`
+	PriorityInferred     bool            `json:"priority_inferred"`
+	Version              int             `json:"version"`
+	Timestamp            time.Time       `json:"timestamp"`
+	Hostname             string          `json:"hostname"`
+	AppName              string          `json:"app_name"`
+	ProcId               string          `json:"proc_id"`
+	MsgId                string          `json:"msg_id"`
+	Message              string          `json:"message"`
+	OriginStructuredData string          `json:"origin_structured_data"`
+	SDElements           []SDElement     `json:"sd_elements"`
+	StructuredData       D               `json:"structured_data"`
+	StructuredErr        error           `json:"structured_err"`
+}
+
+// cursor is the state of a single ParseBytes call, field is the index at
+// which the field being parsed starts.
+type cursor struct {
+	buff  []byte
+	index int
+	field int
+	l     int
+}
+
+// fail reports err at the current index.
+func (c *cursor) fail(field, expected string, err error) error {
+	return common.NewParseError(c.buff[:c.l], c.index, field, expected, err)
 }
 
 type fullDate struct {
@@ -83,63 +104,180 @@ type partialTime struct {
 }
 
 func NewParser[D any]() *Parser[D] {
-	return &Parser[D]{}
+	p := &Parser[D]{
+		rules:           common.DefaultRules,
+		defaultPriority: common.DEFAULTPRIORITY,
+	}
+
+	if common.HasTaggedFields(reflect.TypeOf((*D)(nil)).Elem()) {
+		p.customStructuredDataFunc = UnmarshalStructuredData[D]
+	}
+
+	return p
 }
 
-func (p *Parser[D]) Parse(s string) error {
-	buff := []byte(s)
-	p.buff = buff
-	p.l = int(math.Min(MAXPACKETLEN, float64(len(buff))))
-	p.index = 0
+// configure panics once p parsed a message, a concurrent ParseBytes would
+// see the configuration change under it.
+func (p *Parser[D]) configure() {
+	if p.used.Load() {
+		panic(common.ErrParserInUse)
+	}
+}
 
-	hdr, err := p.parseHeader()
-	if err != nil {
-		return err
+// freeze makes the configuration of p read-only, the flag is only written
+// once so that parsers sharing p do not contend on it.
+func (p *Parser[D]) freeze() {
+	if !p.used.Load() {
+		p.used.Store(true)
 	}
+}
 
-	p.header = hdr
+func (p *Parser[D]) WithStructuredDataFunc(d StructureFunc[D]) {
+	p.configure()
+	p.customStructuredDataFunc = d
+}
 
-	sd, err := p.parseStructuredData()
-	if err != nil {
-		return err
-	}
+// WithMode sets the rules to those of a preset.
+func (p *Parser[D]) WithMode(mode common.Mode) {
+	p.configure()
+	p.rules = mode.Rules()
+}
 
-	p.structuredData = sd
-	p.index++
+// WithRules sets the rules enforced by the parser, for instance
+// common.StrictRules &^ common.RulePrintUSASCII.
+func (p *Parser[D]) WithRules(rules common.Rules) {
+	p.configure()
+	p.rules = rules
+}
 
-	if p.index < p.l {
-		p.message = string(
-			bytes.Trim(
-				p.buff[p.index:p.l], " ",
-			),
-		)
+// WithDefaultPriority accepts a message without PRI whatever the rules, as
+// written by logger(1) and some relays, and gives it pri. The result is
+// flagged PriorityInferred. A pri beyond 191 is ignored.
+func (p *Parser[D]) WithDefaultPriority(pri int) {
+	p.configure()
+	if common.CheckPriority(pri) == nil {
+		p.defaultPriority = pri
+		p.inferPriority = true
 	}
+}
 
-	return nil
+// WithSupportedVersions rejects a VERSION which is not one of versions
+// whatever the rules, RuleVersion alone enforces
+// common.DefaultSupportedVersions.
+func (p *Parser[D]) WithSupportedVersions(versions ...int) {
+	p.configure()
+	p.versions = versions
 }
 
-func (p *Parser[D]) WithStructuredDataFunc(d StructureFunc[D]) {
-	p.customStructuredDataFunc = d
+// WithBestEffort makes ParseBytes return a result along with its error, it
+// holds the fields parsed before the failure and the unparsed remainder,
+// from the failing field on, as MSG. A TIMESTAMP which can not be decoded
+// is left zero.
+func (p *Parser[D]) WithBestEffort() {
+	p.configure()
+	p.bestEffort = true
 }
 
+// Parse parses s and keeps the result for Dump. Parse and Dump are not safe
+// for concurrent use, share a Parser through ParseBytes instead.
+func (p *Parser[D]) Parse(s string) error {
+	res, err := p.ParseBytes([]byte(s))
+	p.result = res
+
+	return err
+}
+
+// Dump returns the result of the last Parse, an empty result when it failed
+// unless WithBestEffort is set.
 func (p *Parser[D]) Dump() *ResultRFC5424[D] {
+	if p.result == nil {
+		return &ResultRFC5424[D]{}
+	}
+
+	res := *p.result
+
+	return &res
+}
+
+// ParseBytes parses b and returns its result, the result does not refer to
+// b once ParseBytes returns. It is safe for concurrent use. The result is
+// nil on error unless WithBestEffort is set.
+func (p *Parser[D]) ParseBytes(b []byte) (*ResultRFC5424[D], error) {
+	var (
+		v        View
+		ts       time.Time
+		sd       string
+		elements []SDElement
+		err      error
+	)
+
+	p.freeze()
+
+	c := &cursor{
+		buff: b,
+		l:    int(math.Min(MAXPACKETLEN, float64(len(b)))),
+	}
+
+	hdrErr := p.parseHeader(c, &v)
+
+	// the TIMESTAMP precedes the field parseHeader may have failed on
+	if v.Timestamp != nil {
+		ts, err = v.Time()
+	}
+
+	if err == nil {
+		err = hdrErr
+	}
+
+	if err != nil && !p.bestEffort {
+		return nil, err
+	}
+
+	sdErr := hdrErr
+	if hdrErr == nil {
+		c.field = c.index
+		sd, elements, sdErr = p.parseStructuredData(c)
+
+		if sdErr != nil && err == nil {
+			err = sdErr
+		}
+	}
+
+	if err != nil && !p.bestEffort {
+		return nil, err
+	}
+
+	if sdErr != nil {
+		p.parseRemainder(c, &v)
+	} else if msgErr := p.parseMessage(c, &v); msgErr != nil {
+		if !p.bestEffort {
+			return nil, msgErr
+		}
+
+		if err == nil {
+			err = msgErr
+		}
+	}
+
 	res := ResultRFC5424[D]{
-		Priority:             p.header.priority.Priority,
-		Facility:             p.header.priority.Facility,
-		Severity:             p.header.priority.Severity,
-		Version:              p.header.version,
-		Timestamp:            p.header.timestamp,
-		Hostname:             p.header.hostname,
-		AppName:              p.header.appName,
-		ProcId:               p.header.procId,
-		MsgId:                p.header.msgId,
-		OriginStructuredData: p.structuredData,
-		Message:              p.message,
+		Priority:             v.Priority,
+		Facility:             v.Facility,
+		Severity:             v.Severity,
+		PriorityInferred:     v.PriorityInferred,
+		Version:              v.Version,
+		Timestamp:            ts,
+		Hostname:             string(v.Hostname),
+		AppName:              string(v.AppName),
+		ProcId:               string(v.ProcId),
+		MsgId:                string(v.MsgId),
+		OriginStructuredData: sd,
+		SDElements:           elements,
+		Message:              string(v.Message),
 		StructuredErr:        nil,
 	}
 
-	if p.customStructuredDataFunc != nil {
-		content, err := p.customStructuredDataFunc(p.structuredData)
+	if p.customStructuredDataFunc != nil && sdErr == nil {
+		content, err := p.customStructuredDataFunc(sd)
 		if err != nil {
 			res.StructuredErr = err
 		} else {
@@ -147,114 +285,326 @@ func (p *Parser[D]) Dump() *ResultRFC5424[D] {
 		}
 	}
 
-	return &res
+	return &res, err
+}
+
+// ToMessage converts the result into the format independent common.Message,
+// NILVALUE fields become empty.
+func (r *ResultRFC5424[D]) ToMessage() *common.Message {
+	return &common.Message{
+		Priority:         r.Priority,
+		Facility:         r.Facility,
+		Severity:         r.Severity,
+		PriorityInferred: r.PriorityInferred,
+		Timestamp:        r.Timestamp,
+		Hostname:         nilToEmpty(r.Hostname),
+		AppName:          nilToEmpty(r.AppName),
+		ProcID:           nilToEmpty(r.ProcId),
+		MsgID:            nilToEmpty(r.MsgId),
+		StructuredData:   r.SDElements,
+		Message:          r.Message,
+	}
+}
+
+func nilToEmpty(s string) string {
+	if s == string(NILVALUE) {
+		return ""
+	}
+
+	return s
 }
 
 // HEADER = PRI VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID
-func (p *Parser[D]) parseHeader() (*header, error) {
-	pri, err := p.parsePriority()
+func (p *Parser[D]) parseHeader(c *cursor, v *View) error {
+	err := p.parsePriority(c, v)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	ver, err := p.parseVersion()
+	c.field = c.index
+	v.Version, err = p.parseVersion(c)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	p.index++
+	if err = p.parseSpace(c, "TIMESTAMP"); err != nil {
+		return err
+	}
 
-	ts, err := p.parseTimestamp()
+	c.field = c.index
+	v.Timestamp, err = p.parseTimestamp(c, v)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	p.index++
+	if err = p.parseSpace(c, "HOSTNAME"); err != nil {
+		return err
+	}
 
-	host, err := p.parseHostname()
+	c.field = c.index
+	v.Hostname, err = p.parseHostname(c)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	appName, err := p.parseAppName()
+	if err = p.parseSpace(c, "APP-NAME"); err != nil {
+		return err
+	}
+
+	c.field = c.index
+	v.AppName, err = p.parseAppName(c)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	p.index++
+	if err = p.parseSpace(c, "PROCID"); err != nil {
+		return err
+	}
 
-	procId, err := p.parseProcId()
+	c.field = c.index
+	v.ProcId, err = p.parseProcId(c)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	p.index++
+	if err = p.parseSpace(c, "MSGID"); err != nil {
+		return err
+	}
 
-	msgId, err := p.parseMsgId()
+	c.field = c.index
+	v.MsgId, err = p.parseMsgId(c)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	hdr := &header{
-		version:   ver,
-		timestamp: *ts,
-		priority:  pri,
-		hostname:  host,
-		procId:    procId,
-		msgId:     msgId,
-		appName:   appName,
+	// a MSGID directly followed by STRUCTURED-DATA has no SP
+	if c.index < c.l && c.buff[c.index] == '[' {
+		return nil
 	}
 
-	return hdr, nil
+	return p.parseSpace(c, "STRUCTURED-DATA")
 }
 
-func (p *Parser[D]) parsePriority() (*common.Priority, error) {
-	return common.ParsePriority(
-		p.buff, &p.index, p.l,
-	)
+func (p *Parser[D]) parsePriority(c *cursor, v *View) error {
+	pri := p.defaultPriority
+
+	if p.missingPriority(c) {
+		v.PriorityInferred = true
+	} else {
+		var err error
+
+		pri, err = common.ParsePriorityValue(
+			c.buff, &c.index, c.l,
+		)
+		if err != nil {
+			return c.fail("PRI", `"<" PRIVAL ">"`, err)
+		}
+
+		if err := p.rules.CheckPriority(c.buff[:c.l], pri); err != nil {
+			return common.NewParseError(c.buff[:c.l], 1, "PRI", "PRIVAL 0 to 191 without leading zero", err)
+		}
+	}
+
+	v.Priority = pri
+	v.Facility = common.Facility(pri / 8)
+	v.Severity = common.Severity(pri % 8)
+
+	return nil
 }
 
-func (p *Parser[D]) parseVersion() (int, error) {
-	return common.ParseVersion(p.buff, &p.index, p.l)
+// missingPriority reports whether the message has no PRI and the default
+// priority is to be assumed.
+func (p *Parser[D]) missingPriority(c *cursor) bool {
+	if p.rules.Has(common.RulePriority) && !p.inferPriority {
+		return false
+	}
+
+	return c.l > 0 && c.buff[0] != '<'
 }
 
-// https://tools.ietf.org/html/rfc5424#section-6.2.3
-func (p *Parser[D]) parseTimestamp() (*time.Time, error) {
-	return parseDate(p.buff, &p.index, p.l)
+func (p *Parser[D]) parseVersion(c *cursor) (int, error) {
+	version, err := common.ParseVersion(c.buff, &c.index, c.l)
+	if err != nil {
+		return version, c.fail("VERSION", "NONZERO-DIGIT 0*2DIGIT", err)
+	}
+
+	if p.versions == nil && !p.rules.Has(common.RuleVersion) {
+		return version, nil
+	}
+
+	versions := p.versions
+	if versions == nil {
+		versions = common.DefaultSupportedVersions
+	}
+
+	if err := versions.Check(version); err != nil {
+		return version, common.NewParseError(c.buff[:c.l], c.field, "VERSION", versions.String(), err)
+	}
+
+	return version, nil
 }
 
-// HOSTNAME = NILVALUE / 1*255PRINTUSASCII
-func (p *Parser[D]) parseHostname() (string, error) {
-	h, err := common.ParseHostname(p.buff, &p.index, p.l)
+// parseSpace steps over the SP ending a field, field names the one that
+// follows. The end of the line is left to the next field.
+func (p *Parser[D]) parseSpace(c *cursor, field string) error {
+	c.field = c.index
+
+	if c.index >= c.l {
+		return nil
+	}
+
+	if c.buff[c.index] != ' ' {
+		return c.fail(field, "SP", common.ErrNoSpace)
+	}
+
+	c.index++
+
+	if p.rules.Has(common.RuleSingleSpace) {
+		if c.index < c.l && c.buff[c.index] == ' ' {
+			return c.fail(field, field, common.ErrExtraSpace)
+		}
 
-	p.index++
+		return nil
+	}
+
+	for c.index < c.l && c.buff[c.index] == ' ' {
+		c.index++
+	}
 
-	return h, err
+	return nil
+}
+
+// TIMESTAMP = NILVALUE / FULL-DATE "T" FULL-TIME
+// https://tools.ietf.org/html/rfc5424#section-6.2.3
+//
+// The timestamp is only delimited here, View.Time checks it.
+func (p *Parser[D]) parseTimestamp(c *cursor, v *View) ([]byte, error) {
+	from := c.index
+
+	if from >= c.l && !p.rules.Has(common.RuleNilValue) {
+		return nilValue, nil
+	}
+
+	for c.index < c.l && c.buff[c.index] != ' ' {
+		c.index++
+	}
+
+	if c.index == from {
+		return nil, c.fail("TIMESTAMP", "NILVALUE / FULL-DATE", common.ErrTimestampUnknownFormat)
+	}
+
+	v.timestamp = from
+
+	return c.buff[from:c.index], nil
+}
+
+// HOSTNAME = NILVALUE / 1*255PRINTUSASCII
+func (p *Parser[D]) parseHostname(c *cursor) ([]byte, error) {
+	return p.parseField(c, "HOSTNAME", 255, ErrInvalidHostname, false)
 }
 
 // APP-NAME = NILVALUE / 1*48PRINTUSASCII
-func (p *Parser[D]) parseAppName() (string, error) {
-	return common.ParseUpToLen(p.buff, &p.index, p.l, 48, ErrInvalidAppName)
+func (p *Parser[D]) parseAppName(c *cursor) ([]byte, error) {
+	return p.parseField(c, "APP-NAME", 48, ErrInvalidAppName, false)
 }
 
 // PROCID = NILVALUE / 1*128PRINTUSASCII
-func (p *Parser[D]) parseProcId() (string, error) {
-	return common.ParseUpToLen(p.buff, &p.index, p.l, 128, ErrInvalidProcId)
+func (p *Parser[D]) parseProcId(c *cursor) ([]byte, error) {
+	return p.parseField(c, "PROCID", 128, ErrInvalidProcId, false)
 }
 
 // MSGID = NILVALUE / 1*32PRINTUSASCII
-func (p *Parser[D]) parseMsgId() (string, error) {
-	return common.ParseUpToLenOrData(
-		p.buff, &p.index, p.l, 32, ErrInvalidMsgId,
+func (p *Parser[D]) parseMsgId(c *cursor) ([]byte, error) {
+	return p.parseField(c, "MSGID", 32, ErrInvalidMsgId, true)
+}
+
+// parseField scans a header field up to the next SP, or up to the "[" of
+// STRUCTURED-DATA when data is set and the rules allow a missing SP. Every
+// header field is followed by another field, so reaching the end of the
+// line fails under RuleNilValue.
+func (p *Parser[D]) parseField(c *cursor, field string, maxLen int, e error, data bool) ([]byte, error) {
+	from := c.index
+
+	if from >= c.l {
+		if p.rules.Has(common.RuleNilValue) {
+			return nil, c.fail(field, "NILVALUE / 1*PRINTUSASCII", e)
+		}
+
+		return nilValue, nil
+	}
+
+	for ; c.index < c.l; c.index++ {
+		ch := c.buff[c.index]
+
+		if ch == ' ' || (data && ch == '[' && !p.rules.Has(common.RuleSingleSpace)) {
+			return c.buff[from:c.index], nil
+		}
+
+		if p.rules.Has(common.RulePrintUSASCII) && !common.IsPrintUSASCII(ch) {
+			return nil, c.fail(field, "PRINTUSASCII", common.ErrNotPrintUSASCII)
+		}
+
+		if p.rules.Has(common.RuleFieldLength) && c.index-from >= maxLen {
+			return nil, c.fail(field, "SP", e)
+		}
+	}
+
+	if p.rules.Has(common.RuleNilValue) {
+		if data && !p.rules.Has(common.RuleSingleSpace) {
+			return nil, c.fail(field, `SP or "["`, e)
+		}
+
+		return nil, c.fail(field, "SP", e)
+	}
+
+	return c.buff[from:c.index], nil
+}
+
+func (p *Parser[D]) parseStructuredData(c *cursor) (string, []SDElement, error) {
+	if c.index >= c.l && !p.rules.Has(common.RuleNilValue) {
+		return string(NILVALUE), nil, nil
+	}
+
+	return parseStructuredData(c.buff, &c.index, c.l)
+}
+
+// parseRemainder keeps everything from the failing field on as MSG.
+func (p *Parser[D]) parseRemainder(c *cursor, v *View) {
+	v.Message = bytes.Trim(
+		c.buff[c.field:c.l], " ",
 	)
 }
 
-func (p *Parser[D]) parseStructuredData() (string, error) {
-	return parseStructuredData(p.buff, &p.index, p.l)
+// MSG = MSG-ANY / MSG-UTF8
+// MSG-UTF8 = BOM UTF-8-STRING
+//
+// The BOM is dropped from the message.
+func (p *Parser[D]) parseMessage(c *cursor, v *View) error {
+	c.index++
+
+	if c.index >= c.l {
+		return nil
+	}
+
+	v.Message = bytes.Trim(
+		c.buff[c.index:c.l], " ",
+	)
+
+	if !bytes.HasPrefix(v.Message, bom) {
+		return nil
+	}
+
+	v.Message = v.Message[len(bom):]
+
+	if p.rules.Has(common.RuleBOM) && !utf8.Valid(v.Message) {
+		return c.fail("MSG", "UTF-8-STRING", ErrInvalidUTF8)
+	}
+
+	return nil
 }
 
 func parseDate(buff []byte, index *int, l int) (*time.Time, error) {
-	if buff[*index] == NILVALUE {
+	if byteAt(buff, *index, l) == NILVALUE {
 		*index++
 		return new(time.Time), nil
 	}
@@ -267,7 +617,7 @@ func parseDate(buff []byte, index *int, l int) (*time.Time, error) {
 		return nil, err
 	}
 
-	if buff[*index] != 'T' {
+	if byteAt(buff, *index, l) != 'T' {
 		return nil, ErrInvalidTimeFormat
 	}
 
@@ -312,7 +662,7 @@ func parseFullDate(buff []byte, cursor *int, l int) (fullDate, error) {
 		return fd, err
 	}
 
-	if buff[*cursor] != '-' {
+	if byteAt(buff, *cursor, l) != '-' {
 		return fd, common.ErrTimestampUnknownFormat
 	}
 
@@ -323,7 +673,7 @@ func parseFullDate(buff []byte, cursor *int, l int) (fullDate, error) {
 		return fd, err
 	}
 
-	if buff[*cursor] != '-' {
+	if byteAt(buff, *cursor, l) != '-' {
 		return fd, common.ErrTimestampUnknownFormat
 	}
 
@@ -350,14 +700,18 @@ func parseYear(buff []byte, index *int, l int) (int, error) {
 		return 0, common.ErrEOL
 	}
 
-	yearSub := buff[*index : *index+yearLen]
+	year := 0
 
-	*index += yearLen
-	year, err := strconv.Atoi(string(yearSub))
-	if err != nil {
-		return 0, ErrYearInvalid
+	for _, c := range buff[*index : *index+yearLen] {
+		if !common.IsDigit(c) {
+			return 0, ErrYearInvalid
+		}
+
+		year = year*10 + int(c-'0')
 	}
 
+	*index += yearLen
+
 	return year, nil
 }
 
@@ -413,7 +767,7 @@ func parsePartialTime(buff []byte, index *int, l int) (*partialTime, error) {
 		return nil, err
 	}
 
-	if buff[*index] != ':' {
+	if byteAt(buff, *index, l) != ':' {
 		return nil, ErrInvalidTimeFormat
 	}
 
@@ -437,7 +791,7 @@ func parsePartialTime(buff []byte, index *int, l int) (*partialTime, error) {
 
 	// ----
 
-	if buff[*index] != '.' {
+	if byteAt(buff, *index, l) != '.' {
 		return pt, nil
 	}
 
@@ -462,7 +816,7 @@ func parseHourMinute(buff []byte, index *int, l int) (int, int, error) {
 		return 0, 0, err
 	}
 
-	if buff[*index] != ':' {
+	if byteAt(buff, *index, l) != ':' {
 		return 0, 0, ErrInvalidTimeFormat
 	}
 	*index++
@@ -527,7 +881,7 @@ func parseSecFrac(buff []byte, index *int, l int) (float64, error) {
 // TIME-OFFSET = "Z" / TIME-NUMOFFSET
 func parseTimeOffset(buff []byte, index *int, l int) (*time.Location, error) {
 
-	if buff[*index] == 'Z' {
+	if byteAt(buff, *index, l) == 'Z' {
 		*index++
 		return time.UTC, nil
 	}
@@ -539,7 +893,7 @@ func parseTimeOffset(buff []byte, index *int, l int) (*time.Location, error) {
 func parseNumericalTimeOffset(buff []byte, index *int, l int) (*time.Location, error) {
 	var loc = new(time.Location)
 
-	sign := buff[*index]
+	sign := byteAt(buff, *index, l)
 
 	if (sign != '+') && (sign != '-') {
 		return loc, ErrTimeZoneInvalid
@@ -573,43 +927,40 @@ func toNSec(sec float64) (int, error) {
 }
 
 // https://tools.ietf.org/html/rfc5424#section-6.3
-func parseStructuredData(buff []byte, index *int, l int) (string, error) {
-	var sdData string
-	var found bool
-
-	if buff[*index] == NILVALUE {
-		*index++
-		return "-", nil
-	}
-
-	if buff[*index] != '[' {
-		return sdData, ErrNoStructuredData
-	}
+func parseStructuredData(buff []byte, index *int, l int) (string, []SDElement, error) {
+	var elements []SDElement
+	var err error
 
 	from := *index
-	to := 0
 
-	for to = from; to < l; to++ {
-		if found {
-			break
+	switch {
+	case from >= l:
+		return "", nil, sdError(buff[:l], from, ErrNoStructuredData)
+	case buff[from] == NILVALUE:
+		*index++
+	case buff[from] == '[':
+		elements, err = parseSDElements(buff, index, l)
+		if err != nil {
+			return "", nil, err
 		}
+	default:
+		return "", nil, sdError(buff[:l], from, ErrNoStructuredData)
+	}
 
-		b := buff[to]
-
-		if b == ']' {
-			switch t := to + 1; {
-			case t == l:
-				found = true
-			case t <= l && buff[t] == ' ':
-				found = true
-			}
-		}
+	// STRUCTURED-DATA is either the last field or followed by SP MSG
+	if *index < l && buff[*index] != ' ' {
+		return "", nil, sdError(buff[:l], *index, ErrSDElementMalformed)
 	}
 
-	if found {
-		*index = to
-		return string(buff[from:to]), nil
+	return string(buff[from:*index]), elements, nil
+}
+
+// byteAt returns the byte at index, or 0 past the end of the line so that
+// a truncated message fails on the next token instead of panicking.
+func byteAt(buff []byte, index int, l int) byte {
+	if index >= l {
+		return 0
 	}
 
-	return sdData, ErrNoStructuredData
+	return buff[index]
 }
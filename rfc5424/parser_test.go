@@ -0,0 +1,102 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// run with -race, one configured parser serves every goroutine
+func TestParseBytesConcurrent(t *testing.T) {
+	p := rfc5424.NewParser[origin]()
+	p.WithDefaultPriority(common.DEFAULTPRIORITY)
+
+	lines := []string{
+		validMessage,
+		sdHeader + originSD + " msg",
+		sdHeader + `[origin@32473 software="beer"] missing params`,
+		"1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 - no PRI",
+		sdHeader + `[a x=y] msg`,
+		"<165>1 2003-13-11T22:14:15.003Z h a - ID47 - x",
+	}
+
+	type outcome struct {
+		res *rfc5424.ResultRFC5424[origin]
+		err string
+	}
+
+	parse := func(line string) outcome {
+		res, err := p.ParseBytes([]byte(line))
+		return outcome{res, fmt.Sprint(err)}
+	}
+
+	want := make([]outcome, len(lines))
+	for i, line := range lines {
+		want[i] = parse(line)
+	}
+
+	var wg sync.WaitGroup
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for n := 0; n < 200; n++ {
+				i := (g + n) % len(lines)
+
+				if got := parse(lines[i]); !reflect.DeepEqual(got, want[i]) {
+					t.Errorf("%q: got %+v, want %+v", lines[i], got, want[i])
+					return
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+}
+
+func TestDumpAfterFailedParse(t *testing.T) {
+	p := rfc5424.NewParser[origin]()
+
+	if err := p.Parse(sdHeader + originSD + " msg"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Parse(sdHeader + `[a x=y] msg`); err == nil {
+		t.Fatal("no error")
+	}
+
+	// nothing is left of the previous message
+	if res := p.Dump(); res == nil || res.Hostname != "" || res.SDElements != nil || res.StructuredData.Software != "" {
+		t.Errorf("got %+v, want an empty result", res)
+	}
+}
+
+func TestWithAfterParse(t *testing.T) {
+	for name, parse := range map[string]func(p *rfc5424.Parser[any]){
+		"ParseBytes": func(p *rfc5424.Parser[any]) { p.ParseBytes([]byte(validMessage)) },
+		"ParseView":  func(p *rfc5424.Parser[any]) { p.ParseView([]byte(validMessage), &rfc5424.View{}) },
+		"Parse":      func(p *rfc5424.Parser[any]) { p.Parse("no priority") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := rfc5424.NewParser[any]()
+			p.WithMode(common.ModeLenient)
+			parse(p)
+
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, common.ErrParserInUse) {
+					t.Errorf("got %v, want a panic with %v", err, common.ErrParserInUse)
+				}
+			}()
+
+			p.WithBestEffort()
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"testing"
+)
+
+func TestDefaultPriority(t *testing.T) {
+	const noPriority = "1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 - 'su root' failed"
+
+	tests := []struct {
+		name     string
+		setup    func(p *rfc5424.Parser[any])
+		line     string
+		err      error
+		priority int
+		facility common.Facility
+		severity common.Severity
+		inferred bool
+	}{
+		{
+			name:  "missing PRI rejected",
+			setup: func(p *rfc5424.Parser[any]) {},
+			line:  noPriority,
+			err:   common.ErrPriorityNoStart,
+		},
+		{
+			name:     "lenient rules assume user.notice",
+			setup:    func(p *rfc5424.Parser[any]) { p.WithMode(common.ModeLenient) },
+			line:     noPriority,
+			priority: common.DEFAULTPRIORITY,
+			facility: common.FacilityUser,
+			severity: common.SeverityNotice,
+			inferred: true,
+		},
+		{
+			name: "default priority whatever the rules",
+			setup: func(p *rfc5424.Parser[any]) {
+				p.WithMode(common.ModeStrict)
+				p.WithDefaultPriority(common.DEFAULTPRIORITY)
+			},
+			line:     noPriority,
+			priority: 13,
+			facility: common.FacilityUser,
+			severity: common.SeverityNotice,
+			inferred: true,
+		},
+		{
+			name:     "custom default priority",
+			setup:    func(p *rfc5424.Parser[any]) { p.WithDefaultPriority(165) },
+			line:     noPriority,
+			priority: 165,
+			facility: common.FacilityLocal4,
+			severity: common.SeverityNotice,
+			inferred: true,
+		},
+		{
+			name:  "default priority beyond 191 ignored",
+			setup: func(p *rfc5424.Parser[any]) { p.WithDefaultPriority(192) },
+			line:  noPriority,
+			err:   common.ErrPriorityNoStart,
+		},
+		{
+			name:     "PRI of the message kept",
+			setup:    func(p *rfc5424.Parser[any]) { p.WithDefaultPriority(165) },
+			line:     "<34>" + noPriority,
+			priority: 34,
+			facility: common.FacilityAuth,
+			severity: common.SeverityCrit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := rfc5424.NewParser[any]()
+			tt.setup(p)
+
+			res, err := p.ParseBytes([]byte(tt.line))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			if res.Priority != tt.priority || res.Facility != tt.facility || res.Severity != tt.severity {
+				t.Errorf("got %d %s.%s, want %d %s.%s", res.Priority, res.Facility, res.Severity, tt.priority, tt.facility, tt.severity)
+			}
+
+			if res.PriorityInferred != tt.inferred || res.ToMessage().PriorityInferred != tt.inferred {
+				t.Errorf("got PriorityInferred %t, want %t", res.PriorityInferred, tt.inferred)
+			}
+
+			if res.Hostname != "mymachine" || res.AppName != "evntslog" {
+				t.Errorf("got %q %q, want mymachine evntslog", res.Hostname, res.AppName)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"strings"
+	"testing"
+)
+
+const validMessage = "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - An application event"
+
+// ruleCases break one rule each, inDefault tells whether ModeDefault
+// rejects them too.
+var ruleCases = []struct {
+	name      string
+	rule      common.Rules
+	line      string
+	err       error
+	inDefault bool
+}{
+	{
+		name:      "PRIVAL beyond 191",
+		rule:      common.RulePriority,
+		line:      "<999>1 2003-10-11T22:14:15.003Z host app - ID47 - hi",
+		err:       common.ErrPriorityBeyondNumber,
+		inDefault: true,
+	},
+	{
+		name:      "missing PRI",
+		rule:      common.RulePriority,
+		line:      "1 2003-10-11T22:14:15.003Z host app - ID47 - hi",
+		err:       common.ErrPriorityNoStart,
+		inDefault: true,
+	},
+	{
+		name:      "missing trailing fields",
+		rule:      common.RuleNilValue,
+		line:      "<165>1 2003-10-11T22:14:15.003Z host",
+		err:       rfc5424.ErrInvalidHostname,
+		inDefault: true,
+	},
+	{
+		name: "extra space",
+		rule: common.RuleSingleSpace,
+		line: "<165>1 2003-10-11T22:14:15.003Z  host app - ID47 - hi",
+		err:  common.ErrExtraSpace,
+	},
+	{
+		name: "APP-NAME too long",
+		rule: common.RuleFieldLength,
+		line: "<165>1 2003-10-11T22:14:15.003Z host " + strings.Repeat("a", 49) + " - ID47 - hi",
+		err:  rfc5424.ErrInvalidAppName,
+	},
+	{
+		name: "HOSTNAME outside PRINTUSASCII",
+		rule: common.RulePrintUSASCII,
+		line: "<165>1 2003-10-11T22:14:15.003Z h\xc3\xa9st app - ID47 - hi",
+		err:  common.ErrNotPrintUSASCII,
+	},
+	{
+		name: "invalid UTF-8 after BOM",
+		rule: common.RuleBOM,
+		line: "<165>1 2003-10-11T22:14:15.003Z host app - ID47 - \xef\xbb\xbfh\xff",
+		err:  rfc5424.ErrInvalidUTF8,
+	},
+	{
+		name: "unsupported VERSION",
+		rule: common.RuleVersion,
+		line: "<165>2 2003-10-11T22:14:15.003Z host app - ID47 - hi",
+		err:  common.ErrVersionUnsupported,
+	},
+	{
+		name: "PRIVAL with leading zero",
+		rule: common.RuleCanonicalPriority,
+		line: "<013>1 2003-10-11T22:14:15.003Z host app - ID47 - hi",
+		err:  common.ErrPriorityLeadingZero,
+	},
+}
+
+func parseWithRules(rules common.Rules, line string) error {
+	p := rfc5424.NewParser[any]()
+	p.WithRules(rules)
+
+	_, err := p.ParseBytes([]byte(line))
+	return err
+}
+
+func TestModesAcceptValidMessage(t *testing.T) {
+	for _, mode := range []common.Mode{common.ModeDefault, common.ModeStrict, common.ModeLenient} {
+		if err := parseWithRules(mode.Rules(), validMessage); err != nil {
+			t.Errorf("%s: %v", mode, err)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	for _, tt := range ruleCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := parseWithRules(common.StrictRules, tt.line); !errors.Is(err, tt.err) {
+				t.Errorf("strict: got %v, want %v", err, tt.err)
+			}
+
+			if err := parseWithRules(common.StrictRules&^tt.rule, tt.line); err != nil {
+				t.Errorf("strict without the rule: %v", err)
+			}
+
+			err := parseWithRules(common.DefaultRules, tt.line)
+			if tt.inDefault && !errors.Is(err, tt.err) {
+				t.Errorf("default: got %v, want %v", err, tt.err)
+			}
+
+			if !tt.inDefault && err != nil {
+				t.Errorf("default: %v", err)
+			}
+
+			if err := parseWithRules(common.LenientRules, tt.line); err != nil {
+				t.Errorf("lenient: %v", err)
+			}
+		})
+	}
+}
+
+func TestPriorityRange(t *testing.T) {
+	tests := []struct {
+		line string
+		err  error
+	}{
+		{line: "<0>1 - - - - - -"},
+		{line: "<191>1 - - - - - -"},
+		{line: "<192>1 - - - - - -", err: common.ErrPriorityBeyondNumber},
+		{line: "<1234>1 - - - - - -", err: common.ErrPriorityTooLong},
+	}
+
+	for _, tt := range tests {
+		if err := parseWithRules(common.DefaultRules, tt.line); !errors.Is(err, tt.err) {
+			t.Errorf("%q: got %v, want %v", tt.line, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package rfc5424
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"strings"
+)
+
+var (
+	ErrSDElementNoStart   = errors.New("No start char found for SD element")
+	ErrSDElementNoEnd     = errors.New("No end char found for SD element")
+	ErrSDIDEmpty          = errors.New("SD-ID field empty")
+	ErrSDIDTooLong        = errors.New("SD-ID field too long")
+	ErrSDIDInvalid        = errors.New("Invalid char in SD-ID")
+	ErrSDIDDuplicate      = errors.New("SD-ID must not exist more than once")
+	ErrParamNameEmpty     = errors.New("PARAM-NAME field empty")
+	ErrParamNameTooLong   = errors.New("PARAM-NAME field too long")
+	ErrParamNameInvalid   = errors.New("Invalid char in PARAM-NAME")
+	ErrParamNoEqual       = errors.New("No equal sign found after PARAM-NAME")
+	ErrParamValueNoQuote  = errors.New("PARAM-VALUE must be quoted")
+	ErrParamValueNoEnd    = errors.New("No end quote found for PARAM-VALUE")
+	ErrSDElementMalformed = errors.New("Malformed SD element")
+)
+
+// SDParam and SDElement live in common so that common.Message can carry
+// them, the aliases keep the RFC 5424 API self-contained.
+type (
+	SDParam   = common.SDParam
+	SDElement = common.SDElement
+)
+
+// StructuredDataError is the ParseError reported for the STRUCTURED-DATA
+// part, it keeps the name used before errors carried their field.
+type StructuredDataError = common.ParseError
+
+// sdError reports err at offset of the STRUCTURED-DATA in buff.
+func sdError(buff []byte, offset int, err error) error {
+	return common.NewParseError(buff, offset, "STRUCTURED-DATA", sdExpected(err), err)
+}
+
+// sdExpected names the token whose absence caused err.
+func sdExpected(err error) string {
+	switch err {
+	case ErrSDElementNoStart:
+		return `"["`
+	case ErrSDElementNoEnd:
+		return `"]"`
+	case ErrSDIDEmpty, ErrSDIDTooLong, ErrSDIDInvalid, ErrSDIDDuplicate:
+		return "SD-ID"
+	case ErrParamNameEmpty, ErrParamNameTooLong, ErrParamNameInvalid:
+		return "PARAM-NAME"
+	case ErrParamNoEqual:
+		return `"="`
+	case ErrParamValueNoQuote, ErrParamValueNoEnd:
+		return "%d34"
+	case ErrSDElementMalformed:
+		return `SP or "]"`
+	case ErrNoStructuredData:
+		return "NILVALUE / 1*SD-ELEMENT"
+	}
+
+	return ""
+}
+
+type sdState int
+
+const (
+	sdStateElement sdState = iota
+	sdStateID
+	sdStateParamName
+	sdStateParamQuote
+	sdStateParamValue
+	sdStateParamEscape
+	sdStateParamEnd
+)
+
+// ParseSDElements parses the STRUCTURED-DATA part of a message,
+// NILVALUE and the empty string yield no elements.
+// https://tools.ietf.org/html/rfc5424#section-6.3
+func ParseSDElements(s string) ([]SDElement, error) {
+	buff := []byte(s)
+	l := len(buff)
+	index := 0
+
+	if l == 0 || (l == 1 && buff[0] == NILVALUE) {
+		return nil, nil
+	}
+
+	elements, err := parseSDElements(buff, &index, l)
+	if err != nil {
+		return nil, err
+	}
+
+	if index != l {
+		return nil, sdError(buff, index, ErrSDElementMalformed)
+	}
+
+	return elements, nil
+}
+
+// STRUCTURED-DATA = NILVALUE / 1*SD-ELEMENT
+// SD-ELEMENT      = "[" SD-ID *(SP SD-PARAM) "]"
+// SD-PARAM        = PARAM-NAME "=" %d34 PARAM-VALUE %d34
+//
+// parseSDElements scans elements until the first byte that can not start a
+// new one, leaving index on that byte.
+func parseSDElements(buff []byte, index *int, l int) ([]SDElement, error) {
+	var (
+		elements []SDElement
+		element  SDElement
+		name     []byte
+		value    []byte
+		start    int
+	)
+
+	seen := make(map[string]struct{})
+	state := sdStateElement
+	i := *index
+
+	fail := func(offset int, err error) ([]SDElement, error) {
+		*index = offset
+		return nil, sdError(buff[:l], offset, err)
+	}
+
+	for ; i < l; i++ {
+		c := buff[i]
+
+		switch state {
+		case sdStateElement:
+			if c != '[' {
+				if len(elements) == 0 {
+					return fail(i, ErrSDElementNoStart)
+				}
+
+				*index = i
+				return elements, nil
+			}
+
+			start = i
+			element = SDElement{}
+			name = name[:0]
+			state = sdStateID
+
+		case sdStateID:
+			switch {
+			case c == ' ' || c == ']':
+				if len(name) == 0 {
+					return fail(i, ErrSDIDEmpty)
+				}
+
+				element.ID = string(name)
+				name = name[:0]
+
+				if c == ' ' {
+					state = sdStateParamName
+					continue
+				}
+
+				if _, ok := seen[element.ID]; ok {
+					return fail(start, ErrSDIDDuplicate)
+				}
+
+				seen[element.ID] = struct{}{}
+				elements = append(elements, element)
+				state = sdStateElement
+			case !isSDNameChar(c):
+				return fail(i, ErrSDIDInvalid)
+			case len(name) >= 32:
+				return fail(i, ErrSDIDTooLong)
+			default:
+				name = append(name, c)
+			}
+
+		case sdStateParamName:
+			switch {
+			case c == '=':
+				if len(name) == 0 {
+					return fail(i, ErrParamNameEmpty)
+				}
+
+				state = sdStateParamQuote
+			case c == ' ' || c == ']':
+				if len(name) == 0 {
+					return fail(i, ErrParamNameEmpty)
+				}
+
+				return fail(i, ErrParamNoEqual)
+			case !isSDNameChar(c):
+				return fail(i, ErrParamNameInvalid)
+			case len(name) >= 32:
+				return fail(i, ErrParamNameTooLong)
+			default:
+				name = append(name, c)
+			}
+
+		case sdStateParamQuote:
+			if c != '"' {
+				return fail(i, ErrParamValueNoQuote)
+			}
+
+			value = value[:0]
+			state = sdStateParamValue
+
+		case sdStateParamValue:
+			switch c {
+			case '\\':
+				state = sdStateParamEscape
+			case '"':
+				element.Params = append(element.Params, SDParam{
+					Name:  string(name),
+					Value: string(value),
+				})
+				name = name[:0]
+				state = sdStateParamEnd
+			default:
+				value = append(value, c)
+			}
+
+		case sdStateParamEscape:
+			// a backslash followed by none of the three escapable characters
+			// is kept as a regular backslash, see RFC 5424 section 6.3.3
+			if !isSDEscapable(c) {
+				value = append(value, '\\')
+			}
+
+			value = append(value, c)
+			state = sdStateParamValue
+
+		case sdStateParamEnd:
+			switch c {
+			case ' ':
+				state = sdStateParamName
+			case ']':
+				if _, ok := seen[element.ID]; ok {
+					return fail(start, ErrSDIDDuplicate)
+				}
+
+				seen[element.ID] = struct{}{}
+				elements = append(elements, element)
+				state = sdStateElement
+			default:
+				return fail(i, ErrSDElementMalformed)
+			}
+		}
+	}
+
+	switch state {
+	case sdStateElement:
+		if len(elements) == 0 {
+			return fail(i, ErrSDElementNoStart)
+		}
+	case sdStateParamValue, sdStateParamEscape:
+		return fail(i, ErrParamValueNoEnd)
+	default:
+		return fail(i, ErrSDElementNoEnd)
+	}
+
+	*index = i
+	return elements, nil
+}
+
+func isSDNameChar(c byte) bool {
+	return c >= 33 && c <= 126 && c != '=' && c != ']' && c != '"'
+}
+
+func isSDEscapable(c byte) bool {
+	return c == '"' || c == '\\' || c == ']'
+}
+
+func formatSDElements(elements []SDElement) string {
+	var sb strings.Builder
+
+	for _, element := range elements {
+		sb.WriteString(element.String())
+	}
+
+	return sb.String()
+}
+
+func checkSDElements(elements []SDElement) error {
+	seen := make(map[string]struct{})
+
+	for _, element := range elements {
+		if err := checkSDName(element.ID, ErrSDIDEmpty, ErrSDIDTooLong, ErrSDIDInvalid); err != nil {
+			return err
+		}
+
+		if _, ok := seen[element.ID]; ok {
+			return ErrSDIDDuplicate
+		}
+
+		seen[element.ID] = struct{}{}
+
+		for _, param := range element.Params {
+			if err := checkSDName(param.Name, ErrParamNameEmpty, ErrParamNameTooLong, ErrParamNameInvalid); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkSDName(name string, empty, tooLong, invalid error) error {
+	if len(name) == 0 {
+		return empty
+	}
+
+	if len(name) > 32 {
+		return tooLong
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isSDNameChar(name[i]) {
+			return invalid
+		}
+	}
+
+	return nil
+}
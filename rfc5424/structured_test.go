@@ -0,0 +1,295 @@
+package rfc5424_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSDElements(t *testing.T) {
+	tests := []struct {
+		sd   string
+		want []rfc5424.SDElement
+	}{
+		{"-", nil},
+		{"", nil},
+		{`[a]`, []rfc5424.SDElement{{ID: "a"}}},
+		{`[a][b]`, []rfc5424.SDElement{{ID: "a"}, {ID: "b"}}},
+		{
+			`[a x="y]z"]`,
+			[]rfc5424.SDElement{{ID: "a", Params: []rfc5424.SDParam{{Name: "x", Value: "y]z"}}}},
+		},
+		{
+			`[a x="1" y=""][b z="[2 3]"]`,
+			[]rfc5424.SDElement{
+				{ID: "a", Params: []rfc5424.SDParam{{Name: "x", Value: "1"}, {Name: "y", Value: ""}}},
+				{ID: "b", Params: []rfc5424.SDParam{{Name: "z", Value: "[2 3]"}}},
+			},
+		},
+		{
+			`[a q="\"" b="\\" c="\]" n="\n"]`,
+			[]rfc5424.SDElement{{ID: "a", Params: []rfc5424.SDParam{
+				{Name: "q", Value: `"`},
+				{Name: "b", Value: `\`},
+				{Name: "c", Value: `]`},
+				{Name: "n", Value: `\n`},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := rfc5424.ParseSDElements(tt.sd)
+		if err != nil {
+			t.Errorf("%s: %v", tt.sd, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.sd, got, tt.want)
+		}
+	}
+}
+
+func TestParseSDElementsErrors(t *testing.T) {
+	tests := []struct {
+		sd     string
+		err    error
+		offset int
+	}{
+		{`a`, rfc5424.ErrSDElementNoStart, 0},
+		{`[a x="y"`, rfc5424.ErrSDElementNoEnd, 8},
+		{`[a`, rfc5424.ErrSDElementNoEnd, 2},
+		{`[a x="y`, rfc5424.ErrParamValueNoEnd, 7},
+		{`[a x="y\`, rfc5424.ErrParamValueNoEnd, 8},
+		{`[a x=y]`, rfc5424.ErrParamValueNoQuote, 5},
+		{`[a x]`, rfc5424.ErrParamNoEqual, 4},
+		{`[a =""]`, rfc5424.ErrParamNameEmpty, 3},
+		{`[]`, rfc5424.ErrSDIDEmpty, 1},
+		{`[a=b]`, rfc5424.ErrSDIDInvalid, 2},
+		{`[a x="y"z]`, rfc5424.ErrSDElementMalformed, 8},
+		{`[a] `, rfc5424.ErrSDElementMalformed, 3},
+		{`[a][b x="1"]x`, rfc5424.ErrSDElementMalformed, 12},
+	}
+
+	for _, tt := range tests {
+		_, err := rfc5424.ParseSDElements(tt.sd)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.sd, err, tt.err)
+			continue
+		}
+
+		var pe *common.ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: got %T, want a ParseError", tt.sd, err)
+			continue
+		}
+
+		if pe.Field != "STRUCTURED-DATA" || pe.Offset != tt.offset {
+			t.Errorf("%s: got %s at %d, want STRUCTURED-DATA at %d", tt.sd, pe.Field, pe.Offset, tt.offset)
+		}
+	}
+}
+
+const sdHeader = "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 "
+
+// the offset of an error is the one of the first violation in the message
+func TestStructuredDataErrorOffset(t *testing.T) {
+	tests := []struct {
+		sd     string
+		err    error
+		offset int
+	}{
+		{`[a x=y] msg`, rfc5424.ErrParamValueNoQuote, 5},
+		{`[a x="y"z] msg`, rfc5424.ErrSDElementMalformed, 8},
+		{`[a x="y] msg`, rfc5424.ErrParamValueNoEnd, 12},
+		{`[a][a] msg`, rfc5424.ErrSDIDDuplicate, 3},
+	}
+
+	for _, tt := range tests {
+		_, err := rfc5424.NewParser[any]().ParseBytes([]byte(sdHeader + tt.sd))
+
+		var pe *common.ParseError
+		if !errors.Is(err, tt.err) || !errors.As(err, &pe) {
+			t.Errorf("%s: got %v, want %v", tt.sd, err, tt.err)
+			continue
+		}
+
+		if want := len(sdHeader) + tt.offset; pe.Offset != want {
+			t.Errorf("%s: got offset %d, want %d", tt.sd, pe.Offset, want)
+		}
+	}
+}
+
+// ParseView skips the elements without decoding them, it must delimit the
+// same STRUCTURED-DATA and MSG as ParseBytes
+func TestStructuredDataViewDelimiting(t *testing.T) {
+	for _, sd := range []string{
+		`-`,
+		`[a]`,
+		`[a][b]`,
+		`[a x="y]z"]`,
+		`[a x="y] z"][b]`,
+		`[a q="\"]" b="\\" c="\]"]`,
+		`[a x="\\"][b y="\""]`,
+		`[a x="[b]"]`,
+	} {
+		line := []byte(sdHeader + sd + " the message")
+
+		res, err := rfc5424.NewParser[any]().ParseBytes(line)
+		if err != nil {
+			t.Errorf("%s: %v", sd, err)
+			continue
+		}
+
+		var v rfc5424.View
+		if err := rfc5424.NewParser[any]().ParseView(line, &v); err != nil {
+			t.Errorf("%s: ParseView: %v", sd, err)
+			continue
+		}
+
+		if string(v.StructuredData) != sd || res.OriginStructuredData != sd {
+			t.Errorf("got %s from ParseView and %s from ParseBytes, want %s", v.StructuredData, res.OriginStructuredData, sd)
+		}
+
+		if string(v.Message) != "the message" || res.Message != "the message" {
+			t.Errorf("%s: got %q and %q, want the message", sd, v.Message, res.Message)
+		}
+	}
+
+	for _, sd := range []string{`[a x="y`, `[a x="y\"]`, `[a`} {
+		line := []byte(sdHeader + sd)
+
+		_, err := rfc5424.NewParser[any]().ParseBytes(line)
+
+		var v rfc5424.View
+		viewErr := rfc5424.NewParser[any]().ParseView(line, &v)
+
+		if err == nil || viewErr == nil || !errors.Is(viewErr, errors.Unwrap(err)) {
+			t.Errorf("%s: got %v from ParseView and %v from ParseBytes", sd, viewErr, err)
+		}
+	}
+}
+
+// SD-ID and PARAM-NAME share the SD-NAME rules, 1*32 PRINTUSASCII except
+// '=', SP, ']' and '"', a '=' in an SD-ID is in TestParseSDElementsErrors
+func TestSDNameRules(t *testing.T) {
+	long := "abcdefghijklmnopqrstuvwxyz012345"
+
+	tests := []struct {
+		name   string
+		id     error
+		param  error
+		offset int
+	}{
+		{"a", nil, nil, 0},
+		{"timeQuality", nil, nil, 0},
+		{"exampleSDID@32473", nil, nil, 0},
+		{"x-y.z_@1!#$%&'()*+,/:;<>?[\\^`{|}", nil, nil, 0},
+		{long, nil, nil, 0},
+		{long + "6", rfc5424.ErrSDIDTooLong, rfc5424.ErrParamNameTooLong, 32},
+		{"a\"b", rfc5424.ErrSDIDInvalid, rfc5424.ErrParamNameInvalid, 1},
+		{"a\tb", rfc5424.ErrSDIDInvalid, rfc5424.ErrParamNameInvalid, 1},
+		{"a\x7fb", rfc5424.ErrSDIDInvalid, rfc5424.ErrParamNameInvalid, 1},
+		{"café", rfc5424.ErrSDIDInvalid, rfc5424.ErrParamNameInvalid, 3},
+	}
+
+	for _, tt := range tests {
+		// the name as the SD-ID, then as the PARAM-NAME of element "a"
+		for _, c := range []struct {
+			sd     string
+			want   rfc5424.SDElement
+			err    error
+			offset int
+		}{
+			{"[" + tt.name + "]", rfc5424.SDElement{ID: tt.name}, tt.id, 1 + tt.offset},
+			{
+				"[a " + tt.name + `="v"]`,
+				rfc5424.SDElement{ID: "a", Params: []rfc5424.SDParam{{Name: tt.name, Value: "v"}}},
+				tt.param,
+				3 + tt.offset,
+			},
+		} {
+			elements, err := rfc5424.ParseSDElements(c.sd)
+			if !errors.Is(err, c.err) {
+				t.Errorf("%q: got %v, want %v", c.sd, err, c.err)
+				continue
+			}
+
+			if c.err == nil {
+				if want := []rfc5424.SDElement{c.want}; !reflect.DeepEqual(elements, want) {
+					t.Errorf("%q: got %+v, want %+v", c.sd, elements, want)
+				}
+
+				continue
+			}
+
+			var pe *common.ParseError
+			if !errors.As(err, &pe) || pe.Offset != c.offset {
+				t.Errorf("%q: got %v, want offset %d", c.sd, err, c.offset)
+			}
+		}
+	}
+}
+
+func TestSDIDDuplicate(t *testing.T) {
+	tests := []struct {
+		sd     string
+		offset int
+	}{
+		{`[a][a]`, 3},
+		{`[a x="1"][b][a y="2"]`, 12},
+		{`[origin@32473][origin@32473 ip="1"]`, 14},
+	}
+
+	for _, tt := range tests {
+		_, err := rfc5424.ParseSDElements(tt.sd)
+
+		var pe *common.ParseError
+		if !errors.Is(err, rfc5424.ErrSDIDDuplicate) || !errors.As(err, &pe) {
+			t.Errorf("%s: got %v, want %v", tt.sd, err, rfc5424.ErrSDIDDuplicate)
+			continue
+		}
+
+		if pe.Offset != tt.offset {
+			t.Errorf("%s: got offset %d, want %d", tt.sd, pe.Offset, tt.offset)
+		}
+	}
+
+	// the same SD-ID with another case or PEN is another element
+	elements, err := rfc5424.ParseSDElements(`[a][A][a@1][a@2]`)
+	if err != nil || len(elements) != 4 {
+		t.Errorf("got %+v, %v, want 4 elements", elements, err)
+	}
+}
+
+func TestResultSDElements(t *testing.T) {
+	sd := `[exampleSDID@32473 iut="3" eventSource="Application"][examplePriority@32473 class="high"]`
+
+	res, err := rfc5424.NewParser[any]().ParseBytes([]byte(sdHeader + sd + " msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []rfc5424.SDElement{
+		{ID: "exampleSDID@32473", Params: []rfc5424.SDParam{{Name: "iut", Value: "3"}, {Name: "eventSource", Value: "Application"}}},
+		{ID: "examplePriority@32473", Params: []rfc5424.SDParam{{Name: "class", Value: "high"}}},
+	}
+
+	if !reflect.DeepEqual(res.SDElements, want) {
+		t.Errorf("got %+v, want %+v", res.SDElements, want)
+	}
+
+	for _, sd := range []string{
+		`[exampleSDID@32473][exampleSDID@32473]`,
+		`[a ` + strings.Repeat("n", 33) + `="v"]`,
+		`[` + strings.Repeat("i", 33) + `]`,
+		`[a n"="v"]`,
+	} {
+		if _, err := rfc5424.NewParser[any]().ParseBytes([]byte(sdHeader + sd + " msg")); err == nil {
+			t.Errorf("%s: no error", sd)
+		}
+	}
+}
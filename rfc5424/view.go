@@ -0,0 +1,181 @@
+package rfc5424
+
+import (
+	"github.com/deadspacewii/psyslog/common"
+	"math"
+	"time"
+)
+
+// View is an RFC 5424 message whose fields are views into the parsed
+// buffer, no string is allocated until the caller converts a field. A View
+// is only valid as long as the buffer is left unchanged and can be reused
+// for every message.
+type View struct {
+	Priority         int
+	Facility         common.Facility
+	Severity         common.Severity
+	PriorityInferred bool
+	Version          int
+	Timestamp        []byte
+	Hostname         []byte
+	AppName          []byte
+	ProcId           []byte
+	MsgId            []byte
+	StructuredData   []byte
+	Message          []byte
+	timestamp        int
+}
+
+// ParseView parses b into v without allocating. TIMESTAMP and
+// STRUCTURED-DATA are only delimited, Time and SDElements decode them on
+// demand. It is safe for concurrent use with distinct views. On error v
+// holds the fields parsed before the failure, WithBestEffort also keeps the
+// remainder as Message.
+func (p *Parser[D]) ParseView(b []byte, v *View) error {
+	p.freeze()
+	*v = View{}
+
+	c := cursor{
+		buff: b,
+		l:    int(math.Min(MAXPACKETLEN, float64(len(b)))),
+	}
+
+	err := p.parseHeader(&c, v)
+
+	if err == nil {
+		c.field = c.index
+		v.StructuredData, err = p.scanStructuredData(&c)
+	}
+
+	if err != nil {
+		if p.bestEffort {
+			p.parseRemainder(&c, v)
+		}
+
+		return err
+	}
+
+	return p.parseMessage(&c, v)
+}
+
+// Time decodes the TIMESTAMP, NILVALUE yields the zero time.
+func (v *View) Time() (time.Time, error) {
+	index := 0
+	l := len(v.Timestamp)
+
+	ts, err := parseDate(v.Timestamp, &index, l)
+	if err == nil && index != l {
+		err = common.ErrTimestampUnknownFormat
+	}
+
+	if err != nil {
+		e := common.NewParseError(v.Timestamp, index, "TIMESTAMP", timestampExpected(err), err)
+		e.Offset += v.timestamp
+
+		return time.Time{}, e
+	}
+
+	return *ts, nil
+}
+
+// timestampExpected names the TIMESTAMP part whose absence caused err.
+func timestampExpected(err error) string {
+	switch err {
+	case ErrYearInvalid:
+		return "DATE-FULLYEAR"
+	case ErrMonthInvalid:
+		return "DATE-MONTH"
+	case ErrDayInvalid:
+		return "DATE-MDAY"
+	case ErrHourInvalid:
+		return "TIME-HOUR"
+	case ErrMinuteInvalid:
+		return "TIME-MINUTE"
+	case ErrSecondInvalid:
+		return "TIME-SECOND"
+	case ErrSecFracInvalid:
+		return "TIME-SECFRAC"
+	case ErrTimeZoneInvalid:
+		return "TIME-OFFSET"
+	}
+
+	return "NILVALUE / FULL-DATE \"T\" FULL-TIME"
+}
+
+// SDElements decodes the STRUCTURED-DATA, offsets of a StructuredDataError
+// are relative to it.
+func (v *View) SDElements() ([]SDElement, error) {
+	return ParseSDElements(string(v.StructuredData))
+}
+
+func (p *Parser[D]) scanStructuredData(c *cursor) ([]byte, error) {
+	if c.index >= c.l && !p.rules.Has(common.RuleNilValue) {
+		return nilValue, nil
+	}
+
+	return scanStructuredData(c.buff, &c.index, c.l)
+}
+
+// scanStructuredData delimits the STRUCTURED-DATA the way parseStructuredData
+// does, elements are skipped over without being decoded.
+func scanStructuredData(buff []byte, index *int, l int) ([]byte, error) {
+	from := *index
+
+	switch {
+	case from >= l:
+		return nil, sdError(buff[:l], from, ErrNoStructuredData)
+	case buff[from] == NILVALUE:
+		*index++
+	case buff[from] == '[':
+		if err := skipSDElements(buff, index, l); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, sdError(buff[:l], from, ErrNoStructuredData)
+	}
+
+	if *index < l && buff[*index] != ' ' {
+		return nil, sdError(buff[:l], *index, ErrSDElementMalformed)
+	}
+
+	return buff[from:*index], nil
+}
+
+func skipSDElements(buff []byte, index *int, l int) error {
+	i := *index
+
+	for i < l && buff[i] == '[' {
+		quoted := false
+
+		for i++; ; i++ {
+			if i >= l {
+				if quoted {
+					return sdError(buff[:l], i, ErrParamValueNoEnd)
+				}
+
+				return sdError(buff[:l], i, ErrSDElementNoEnd)
+			}
+
+			c := buff[i]
+
+			if quoted && c == '\\' {
+				i++
+				continue
+			}
+
+			if c == '"' {
+				quoted = !quoted
+				continue
+			}
+
+			if !quoted && c == ']' {
+				i++
+				break
+			}
+		}
+	}
+
+	*index = i
+
+	return nil
+}
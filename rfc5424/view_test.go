@@ -0,0 +1,40 @@
+package rfc5424_test
+
+import (
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"testing"
+)
+
+var viewMessage = []byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"] An application event log entry`)
+
+func TestParseViewAllocs(t *testing.T) {
+	p := rfc5424.NewParser[any]()
+
+	var v rfc5424.View
+
+	if err := p.ParseView(viewMessage, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		p.ParseView(viewMessage, &v)
+	})
+
+	if allocs > 0 {
+		t.Errorf("ParseView allocates %.0f times per message, want 0", allocs)
+	}
+}
+
+func BenchmarkParseView(b *testing.B) {
+	p := rfc5424.NewParser[any]()
+
+	var v rfc5424.View
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := p.ParseView(viewMessage, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package psyslog
+
+import (
+	"bytes"
+	"errors"
+	"github.com/deadspacewii/psyslog/transport"
+	"io"
+)
+
+// Record is a single message read by a Scanner, Err is set when the record
+// could not be framed or parsed.
+type Record struct {
+	Result *Result
+	Raw    []byte
+	Line   int
+	Err    error
+}
+
+// Scanner reads messages from a log file, a pipe or any other stream. By
+// default the framing is detected from the first record: octet counted
+// when it starts with a digit, LF terminated otherwise. Per record errors
+// are reported through Record and scanning goes on.
+//
+//	scanner := psyslog.NewScanner(os.Stdin)
+//	for scanner.Scan() {
+//		record := scanner.Record()
+//		...
+//	}
+//	if err := scanner.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	r       io.Reader
+	reader  *transport.Reader
+	framing transport.Framing
+	trailer byte
+	maxLen  int
+	parse   ParseFunc
+	record  Record
+	err     error
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:       r,
+		framing: transport.FramingAuto,
+		trailer: transport.TRAILER,
+		maxLen:  transport.MAXFRAMELEN,
+		parse:   Parse,
+	}
+}
+
+func (s *Scanner) WithFraming(framing transport.Framing) *Scanner {
+	s.framing = framing
+	return s
+}
+
+// WithTrailer sets the byte ending non-transparent records, use 0 for NUL
+// separated streams.
+func (s *Scanner) WithTrailer(trailer byte) *Scanner {
+	s.trailer = trailer
+	return s
+}
+
+// WithMaxRecordLen sets the record size limit, longer records are skipped
+// and reported with transport.ErrFrameTooLong.
+func (s *Scanner) WithMaxRecordLen(l int) *Scanner {
+	s.maxLen = l
+	return s
+}
+
+// WithFormat selects the parser used for every record, FormatUnknown
+// auto-detects the format.
+func (s *Scanner) WithFormat(format Format) *Scanner {
+	s.parse = ParseFuncFor(format)
+	return s
+}
+
+func (s *Scanner) WithParseFunc(f ParseFunc) *Scanner {
+	s.parse = f
+	return s
+}
+
+// Scan advances to the next record, it returns false at the end of the
+// stream or when the stream can not be read any further.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	if s.reader == nil {
+		s.reader = transport.NewReader(s.r, s.framing).
+			WithTrailer(s.trailer).
+			WithMaxFrameLen(s.maxLen)
+	}
+
+	frame, err := s.reader.ReadFrame()
+
+	s.record = Record{Line: s.reader.Line()}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, transport.ErrFrameTooLong):
+		s.record.Err = err
+		return true
+	case err == io.EOF:
+		return false
+	default:
+		s.err = err
+		return false
+	}
+
+	s.record.Raw = bytes.TrimRight(frame, "\r")
+	s.record.Result, s.record.Err = s.parse(s.record.Raw)
+
+	return true
+}
+
+// Record returns the record read by the last Scan, Raw is only valid until
+// the next call.
+func (s *Scanner) Record() *Record {
+	return &s.record
+}
+
+// Err returns the error which stopped the Scanner, nil at the end of the
+// stream.
+func (s *Scanner) Err() error {
+	return s.err
+}
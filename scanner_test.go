@@ -0,0 +1,111 @@
+package psyslog_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/transport"
+	"io"
+	"strings"
+	"testing"
+)
+
+type scanned struct {
+	line   int
+	format psyslog.Format
+	err    error
+}
+
+func scanAll(s *psyslog.Scanner) []scanned {
+	var records []scanned
+
+	for s.Scan() {
+		r := s.Record()
+
+		got := scanned{line: r.Line, err: r.Err}
+		if r.Result != nil {
+			got.format = r.Result.Format
+		}
+
+		records = append(records, got)
+	}
+
+	return records
+}
+
+func TestScanner(t *testing.T) {
+	stream := strings.Join([]string{
+		"<34>Oct 11 22:14:15 mymachine su: 'su root' failed\r",
+		"",
+		"<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - An application event",
+		"no priority",
+		"<34>Oct 11 22:14:15 mymachine su: " + strings.Repeat("x", 128),
+		"<34>Oct 11 22:14:16 mymachine su: last, without LF",
+	}, "\n")
+
+	s := psyslog.NewScanner(strings.NewReader(stream)).WithMaxRecordLen(128)
+
+	want := []scanned{
+		{line: 1, format: psyslog.FormatRFC3164},
+		{line: 3, format: psyslog.FormatRFC5424},
+		{line: 4, err: common.ErrPriorityNoStart},
+		{line: 5, err: transport.ErrFrameTooLong},
+		{line: 6, format: psyslog.FormatRFC3164},
+	}
+
+	got := scanAll(s)
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(got), len(want), got)
+	}
+
+	for i := range want {
+		if got[i].line != want[i].line || got[i].format != want[i].format || !errors.Is(got[i].err, want[i].err) {
+			t.Errorf("record %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		t.Errorf("got %v at the end of the stream", err)
+	}
+}
+
+func TestScannerOctetCounted(t *testing.T) {
+	msg := "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - multi\nline"
+	stream := string(transport.AppendFrame(nil, transport.FramingOctetCounting, []byte(msg))) + "\n" +
+		string(transport.AppendFrame(nil, transport.FramingOctetCounting, []byte(msg)))
+
+	s := psyslog.NewScanner(strings.NewReader(stream)).WithFormat(psyslog.FormatRFC5424)
+
+	got := scanAll(s)
+	want := []scanned{
+		{line: 1, format: psyslog.FormatRFC5424},
+		{line: 3, format: psyslog.FormatRFC5424},
+	}
+
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestScannerStops(t *testing.T) {
+	s := psyslog.NewScanner(strings.NewReader("5 <1>ab05 <1>cd")).WithFraming(transport.FramingOctetCounting)
+
+	if got := scanAll(s); len(got) != 1 {
+		t.Errorf("got %d records, want 1", len(got))
+	}
+
+	if err := s.Err(); !errors.Is(err, transport.ErrInvalidOctetCount) {
+		t.Errorf("got %v, want %v", err, transport.ErrInvalidOctetCount)
+	}
+
+	if s.Scan() {
+		t.Error("Scan went on after the error")
+	}
+
+	s = psyslog.NewScanner(strings.NewReader("9 <1>ab"))
+	scanAll(s)
+
+	if err := s.Err(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,67 @@
+// Package server receives syslog messages from the network and hands the
+// parsed results to a Handler.
+package server
+
+import (
+	"crypto/x509"
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"net"
+)
+
+var (
+	ErrServerNotListening = errors.New("Server is not listening")
+	ErrServerListening    = errors.New("Server is already listening")
+)
+
+// ParseFunc turns one received frame into a result.
+type ParseFunc = psyslog.ParseFunc
+
+// Message is a single frame received by a server, Err is set when the frame
+// failed to parse. The peer fields are only set for TLS connections with a
+// client certificate and Credentials only for Unix sockets.
+type Message struct {
+	Result          *psyslog.Result
+	Raw             []byte
+	Addr            net.Addr
+	Err             error
+	PeerSubject     string
+	PeerCertificate *x509.Certificate
+	Credentials     *Credentials
+}
+
+// Credentials identify the local process which sent a message over a Unix
+// socket, see UnixServer.
+type Credentials struct {
+	Pid int32
+	Uid uint32
+	Gid uint32
+}
+
+// Handler is called once per received frame, it must not retain msg.Raw
+// beyond the call unless it copies it.
+type Handler func(msg *Message)
+
+// ErrorHandler is called with per connection errors, a skipped frame or the
+// error that ended a connection. The server keeps accepting connections.
+type ErrorHandler func(addr net.Addr, err error)
+
+// ParseFuncFor returns the parser of format, FormatUnknown auto-detects the
+// format of every frame with psyslog.Parse.
+func ParseFuncFor(format psyslog.Format) ParseFunc {
+	return psyslog.ParseFuncFor(format)
+}
+
+// trimFrame drops the trailing LF, CR and NUL many senders append.
+func trimFrame(b []byte) []byte {
+	for len(b) > 0 {
+		switch b[len(b)-1] {
+		case '\n', '\r', 0:
+			b = b[:len(b)-1]
+		default:
+			return b
+		}
+	}
+
+	return b
+}
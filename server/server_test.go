@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	"context"
+	"github.com/deadspacewii/psyslog/server"
+	"net"
+	"testing"
+	"time"
+)
+
+// listener is implemented by every server.
+type listener interface {
+	Listen() error
+	Serve(ctx context.Context) error
+}
+
+// serve listens and serves srv until the test ends.
+func serve(t *testing.T, srv listener) {
+	t.Helper()
+
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		srv.Serve(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+type event struct {
+	msg *server.Message
+	err error
+}
+
+// collect returns handlers sending the messages and the errors of a server
+// to events, Raw is copied as the server reuses it.
+func collect() (server.Handler, server.ErrorHandler, chan event) {
+	events := make(chan event, 16)
+
+	handler := func(msg *server.Message) {
+		m := *msg
+		m.Raw = append([]byte(nil), msg.Raw...)
+		events <- event{msg: &m}
+	}
+
+	errHandler := func(_ net.Addr, err error) {
+		events <- event{err: err}
+	}
+
+	return handler, errHandler, events
+}
+
+func nextEvent(t *testing.T, events chan event) event {
+	t.Helper()
+
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the server")
+	}
+
+	return event{}
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/transport"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrIdleTimeout = errors.New("Connection idle timeout")
+
+// HANDSHAKETIMEOUT bounds the TLS handshake when no idle timeout is set.
+const HANDSHAKETIMEOUT = 30 * time.Second
+
+// TCPServer receives messages framed as described in
+// https://tools.ietf.org/html/rfc6587, the framing of every connection is
+// detected from its first frame unless WithFraming picks one.
+type TCPServer struct {
+	addr        string
+	handler     Handler
+	errHandler  ErrorHandler
+	parse       ParseFunc
+	framing     transport.Framing
+	maxLen      int
+	idleTimeout time.Duration
+	tlsConfig   *tls.Config
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+func NewTCPServer(addr string, handler Handler) *TCPServer {
+	return &TCPServer{
+		addr:    addr,
+		handler: handler,
+		parse:   psyslog.Parse,
+		framing: transport.FramingAuto,
+		maxLen:  transport.MAXFRAMELEN,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// NewTLSServer returns a server for syslog over TLS as described in
+// https://tools.ietf.org/html/rfc5425, frames are octet counted. Mutual
+// TLS and pinning are set up through config, see transport.PinServer.
+// Messages carry the subject of the peer certificate.
+func NewTLSServer(addr string, config *tls.Config, handler Handler) *TCPServer {
+	s := NewTCPServer(addr, handler)
+	s.tlsConfig = config
+	s.framing = transport.FramingOctetCounting
+	return s
+}
+
+// WithFormat selects the parser used for every frame, FormatUnknown
+// auto-detects the format.
+func (s *TCPServer) WithFormat(format psyslog.Format) {
+	s.parse = ParseFuncFor(format)
+}
+
+func (s *TCPServer) WithParseFunc(f ParseFunc) {
+	s.parse = f
+}
+
+func (s *TCPServer) WithFraming(framing transport.Framing) {
+	s.framing = framing
+}
+
+// WithMaxFrameLen sets the frame size limit, longer frames are skipped and
+// reported to the error handler. It defaults to MAXFRAMELEN.
+func (s *TCPServer) WithMaxFrameLen(l int) {
+	s.maxLen = l
+}
+
+// WithIdleTimeout closes connections that send nothing for d, zero
+// disables the timeout.
+func (s *TCPServer) WithIdleTimeout(d time.Duration) {
+	s.idleTimeout = d
+}
+
+func (s *TCPServer) WithErrorHandler(h ErrorHandler) {
+	s.errHandler = h
+}
+
+// Listen binds the address, Addr is valid once it returns.
+func (s *TCPServer) Listen() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	return s.setListener(listener)
+}
+
+func (s *TCPServer) setListener(listener net.Listener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		listener.Close()
+		return ErrServerListening
+	}
+
+	s.listener = listener
+	return nil
+}
+
+// Addr returns the bound address, nil before Listen.
+func (s *TCPServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+
+	return s.listener.Addr()
+}
+
+// Serve accepts connections until ctx is done, then closes the open
+// connections and waits for their handlers to return.
+func (s *TCPServer) Serve(ctx context.Context) error {
+	s.mu.Lock()
+	listener := s.listener
+	s.mu.Unlock()
+
+	if listener == nil {
+		return ErrServerNotListening
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			listener.Close()
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		s.closeConns()
+		s.wg.Wait()
+
+		s.mu.Lock()
+		s.listener = nil
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+
+			listener.Close()
+			return err
+		}
+
+		s.trackConn(conn)
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			defer s.untrackConn(conn)
+
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// ListenAndServe binds the address and serves until ctx is done.
+func (s *TCPServer) ListenAndServe(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	return s.Serve(ctx)
+}
+
+func (s *TCPServer) serveConn(conn net.Conn) {
+	addr := conn.RemoteAddr()
+
+	var peer *x509.Certificate
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		var err error
+
+		peer, err = s.handshake(tlsConn)
+		if err != nil {
+			s.reportError(addr, err)
+			return
+		}
+	}
+
+	reader := transport.NewReader(conn, s.framing).WithMaxFrameLen(s.maxLen)
+
+	for {
+		if s.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		}
+
+		frame, err := reader.ReadFrame()
+
+		switch {
+		case err == nil:
+		case errors.Is(err, transport.ErrFrameTooLong):
+			s.reportError(addr, err)
+			continue
+		case err == io.EOF || errors.Is(err, net.ErrClosed):
+			return
+		default:
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = ErrIdleTimeout
+			}
+
+			s.reportError(addr, err)
+			return
+		}
+
+		raw := trimFrame(frame)
+		res, err := s.parse(raw)
+
+		msg := &Message{
+			Result: res,
+			Raw:    raw,
+			Addr:   addr,
+			Err:    err,
+		}
+
+		if peer != nil {
+			msg.PeerCertificate = peer
+			msg.PeerSubject = peer.Subject.String()
+		}
+
+		s.handler(msg)
+	}
+}
+
+func (s *TCPServer) handshake(conn *tls.Conn) (*x509.Certificate, error) {
+	timeout := s.idleTimeout
+	if timeout <= 0 {
+		timeout = HANDSHAKETIMEOUT
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return transport.PeerCertificate(conn.ConnectionState()), nil
+}
+
+func (s *TCPServer) reportError(addr net.Addr, err error) {
+	if s.errHandler != nil {
+		s.errHandler(addr, err)
+	}
+}
+
+func (s *TCPServer) trackConn(conn net.Conn) {
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *TCPServer) untrackConn(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+
+	conn.Close()
+}
+
+func (s *TCPServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,128 @@
+package server_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/client"
+	"github.com/deadspacewii/psyslog/server"
+	"github.com/deadspacewii/psyslog/transport"
+	"net"
+	"strings"
+	"testing"
+)
+
+const (
+	rfc3164Message = "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8"
+	rfc5424Message = "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 - An application event"
+)
+
+func startTCPServer(t *testing.T, maxLen int) (string, chan event) {
+	t.Helper()
+
+	handler, errHandler, events := collect()
+
+	srv := server.NewTCPServer("127.0.0.1:0", handler)
+	srv.WithErrorHandler(errHandler)
+	srv.WithMaxFrameLen(maxLen)
+
+	serve(t, srv)
+
+	return srv.Addr().String(), events
+}
+
+func TestTCPRoundTrip(t *testing.T) {
+	for _, framing := range []transport.Framing{transport.FramingOctetCounting, transport.FramingNonTransparent} {
+		t.Run(framing.String(), func(t *testing.T) {
+			addr, events := startTCPServer(t, transport.MAXFRAMELEN)
+
+			w, err := client.NewWriter("tcp://" + addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			w.WithFraming(framing)
+
+			for _, line := range []string{rfc5424Message, rfc3164Message} {
+				if _, err := w.Write([]byte(line + "\n")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, want := range []psyslog.Format{psyslog.FormatRFC5424, psyslog.FormatRFC3164} {
+				e := nextEvent(t, events)
+				if e.err != nil || e.msg.Err != nil {
+					t.Fatalf("got %v, %v", e.err, e.msg.Err)
+				}
+
+				if e.msg.Result.Format != want {
+					t.Errorf("got %s, want %s", e.msg.Result.Format, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTCPFrameTooLong(t *testing.T) {
+	long := rfc5424Message + strings.Repeat("x", 32)
+
+	for _, framing := range []transport.Framing{transport.FramingOctetCounting, transport.FramingNonTransparent} {
+		t.Run(framing.String(), func(t *testing.T) {
+			addr, events := startTCPServer(t, len(rfc5424Message))
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			var stream []byte
+			for _, msg := range []string{rfc5424Message, long, rfc5424Message} {
+				stream = transport.AppendFrame(stream, framing, []byte(msg))
+			}
+
+			if _, err := conn.Write(stream); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 3; i++ {
+				e := nextEvent(t, events)
+
+				if i == 1 {
+					if !errors.Is(e.err, transport.ErrFrameTooLong) {
+						t.Fatalf("frame %d: got %v, want %v", i, e.err, transport.ErrFrameTooLong)
+					}
+
+					continue
+				}
+
+				if e.err != nil || string(e.msg.Raw) != rfc5424Message {
+					t.Fatalf("frame %d: got %v, want %q", i, e.err, rfc5424Message)
+				}
+			}
+		})
+	}
+}
+
+// the framing detected on the first frame holds for the connection
+func TestTCPFramingPerConnection(t *testing.T) {
+	addr, events := startTCPServer(t, transport.MAXFRAMELEN)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	lines := []string{rfc3164Message, "12 bottles of beer on the wall, long line"}
+
+	if _, err := conn.Write([]byte(strings.Join(lines, "\n") + "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range lines {
+		if e := nextEvent(t, events); e.err != nil || string(e.msg.Raw) != want {
+			t.Fatalf("got %v, want %q", e.err, want)
+		}
+	}
+}
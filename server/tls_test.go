@@ -0,0 +1,155 @@
+package server_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"github.com/deadspacewii/psyslog/client"
+	"github.com/deadspacewii/psyslog/server"
+	"github.com/deadspacewii/psyslog/transport"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// selfSigned returns a certificate for cn and its SHA-256 fingerprint.
+func selfSigned(t *testing.T, cn string) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, transport.Fingerprint(leaf)
+}
+
+// startTLSServer serves a server pinned to clientPin until the test ends.
+func startTLSServer(t *testing.T, cert tls.Certificate, clientPin string) (string, chan event) {
+	t.Helper()
+
+	config, err := transport.PinServer(&tls.Config{Certificates: []tls.Certificate{cert}}, clientPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, errHandler, events := collect()
+
+	srv := server.NewTLSServer("127.0.0.1:0", config, handler)
+	srv.WithErrorHandler(errHandler)
+
+	serve(t, srv)
+
+	return srv.Addr().String(), events
+}
+
+func TestTLSPinning(t *testing.T) {
+	serverCert, serverPin := selfSigned(t, "server")
+	clientCert, clientPin := selfSigned(t, "pinned client")
+	otherCert, _ := selfSigned(t, "other client")
+
+	addr, events := startTLSServer(t, serverCert, clientPin)
+
+	tests := []struct {
+		name  string
+		certs []tls.Certificate
+		want  error
+	}{
+		{name: "pinned client", certs: []tls.Certificate{clientCert}},
+		{name: "wrong pin", certs: []tls.Certificate{otherCert}, want: transport.ErrFingerprintPinned},
+		{name: "anonymous client"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := transport.PinClient(&tls.Config{Certificates: tt.certs}, serverPin)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// with TLS 1.3 a refused client only learns it on its next read,
+			// the server side tells the outcome
+			c, err := client.DialTLS(addr, config)
+			if err == nil {
+				c.Send([]byte(rfc5424Message))
+				defer c.Close()
+			}
+
+			e := nextEvent(t, events)
+
+			if tt.name == "pinned client" {
+				if e.err != nil {
+					t.Fatalf("pinned client refused: %v", e.err)
+				}
+
+				if e.msg.PeerSubject != "CN=pinned client" || e.msg.Err != nil {
+					t.Fatalf("got subject %q, err %v", e.msg.PeerSubject, e.msg.Err)
+				}
+
+				return
+			}
+
+			if e.err == nil {
+				t.Fatalf("message handled with subject %q, want the handshake refused", e.msg.PeerSubject)
+			}
+
+			if tt.want != nil && !errors.Is(e.err, tt.want) {
+				t.Fatalf("got %v, want %v", e.err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSPinClientRefusesServer(t *testing.T) {
+	serverCert, _ := selfSigned(t, "server")
+	clientCert, clientPin := selfSigned(t, "client")
+	_, otherPin := selfSigned(t, "other server")
+
+	addr, _ := startTLSServer(t, serverCert, clientPin)
+
+	config, err := transport.PinClient(&tls.Config{Certificates: []tls.Certificate{clientCert}}, otherPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.DialTLS(addr, config); !errors.Is(err, transport.ErrFingerprintPinned) {
+		t.Fatalf("got %v, want %v", err, transport.ErrFingerprintPinned)
+	}
+}
+
+func TestPinFingerprintFormat(t *testing.T) {
+	for _, fp := range []string{"", "SHA-256:zz", "MD5:00:11", "SHA-1:00:11"} {
+		if _, err := transport.PinServer(nil, fp); !errors.Is(err, transport.ErrFingerprintFormat) {
+			t.Errorf("%q: got %v, want %v", fp, err, transport.ErrFingerprintFormat)
+		}
+	}
+
+	if _, err := transport.PinClient(nil); !errors.Is(err, transport.ErrFingerprintFormat) {
+		t.Errorf("no pins: got %v, want %v", err, transport.ErrFingerprintFormat)
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"net"
+	"sync"
+)
+
+// UDPServer receives one message per datagram as described in
+// https://tools.ietf.org/html/rfc5426
+type UDPServer struct {
+	addr    string
+	handler Handler
+	parse   ParseFunc
+	maxLen  int
+
+	mu   sync.Mutex
+	conn *net.UDPConn
+}
+
+func NewUDPServer(addr string, handler Handler) *UDPServer {
+	return &UDPServer{
+		addr:    addr,
+		handler: handler,
+		parse:   psyslog.Parse,
+		maxLen:  rfc5424.MAXPACKETLEN,
+	}
+}
+
+// WithFormat selects the parser used for every datagram, FormatUnknown
+// auto-detects the format.
+func (s *UDPServer) WithFormat(format psyslog.Format) {
+	s.parse = ParseFuncFor(format)
+}
+
+func (s *UDPServer) WithParseFunc(f ParseFunc) {
+	s.parse = f
+}
+
+// WithMaxPacketLen sets the read buffer size, longer datagrams are
+// truncated. It defaults to MAXPACKETLEN.
+func (s *UDPServer) WithMaxPacketLen(l int) {
+	s.maxLen = l
+}
+
+// Listen binds the address, Addr is valid once it returns.
+func (s *UDPServer) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		return ErrServerListening
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// Addr returns the bound address, nil before Listen.
+func (s *UDPServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.LocalAddr()
+}
+
+// Serve reads datagrams until ctx is done, the handler runs on the reading
+// goroutine so no message is in flight once Serve returns.
+func (s *UDPServer) Serve(ctx context.Context) error {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+
+	if conn == nil {
+		return ErrServerNotListening
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}()
+
+	buff := make([]byte, s.maxLen)
+
+	for {
+		n, addr, err := conn.ReadFromUDP(buff)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+
+			conn.Close()
+			return err
+		}
+
+		raw := trimFrame(buff[:n])
+		res, err := s.parse(raw)
+
+		s.handler(&Message{
+			Result: res,
+			Raw:    raw,
+			Addr:   addr,
+			Err:    err,
+		})
+	}
+}
+
+// ListenAndServe binds the address and serves until ctx is done.
+func (s *UDPServer) ListenAndServe(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	return s.Serve(ctx)
+}
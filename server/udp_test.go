@@ -0,0 +1,76 @@
+package server_test
+
+import (
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/client"
+	"github.com/deadspacewii/psyslog/server"
+	"net"
+	"testing"
+)
+
+func TestUDPRoundTrip(t *testing.T) {
+	handler, _, events := collect()
+	srv := server.NewUDPServer("127.0.0.1:0", handler)
+	serve(t, srv)
+
+	addr := srv.Addr().String()
+
+	w, err := client.NewWriter("udp://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{rfc5424Message, rfc3164Message} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		raw    string
+		format psyslog.Format
+	}{
+		{rfc5424Message, psyslog.FormatRFC5424},
+		{rfc3164Message, psyslog.FormatRFC3164},
+	} {
+		e := nextEvent(t, events)
+		if e.msg.Err != nil {
+			t.Fatal(e.msg.Err)
+		}
+
+		if string(e.msg.Raw) != tt.raw || e.msg.Result.Format != tt.format {
+			t.Errorf("got %s %q, want %s %q", e.msg.Result.Format, e.msg.Raw, tt.format, tt.raw)
+		}
+	}
+}
+
+func TestUDPTrailerAndParseError(t *testing.T) {
+	handler, _, events := collect()
+	srv := server.NewUDPServer("127.0.0.1:0", handler)
+	serve(t, srv)
+
+	addr := srv.Addr().String()
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, datagram := range []string{rfc5424Message + "\r\n\x00", "no priority"} {
+		if _, err := conn.Write([]byte(datagram)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := nextEvent(t, events)
+	if e.msg.Err != nil || string(e.msg.Raw) != rfc5424Message {
+		t.Errorf("got %q, %v, want the trailer dropped", e.msg.Raw, e.msg.Err)
+	}
+
+	e = nextEvent(t, events)
+	if e.msg.Err == nil || e.msg.Result != nil {
+		t.Errorf("got %v, %v, want a parse error", e.msg.Result, e.msg.Err)
+	}
+}
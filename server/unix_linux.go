@@ -0,0 +1,358 @@
+//go:build linux
+
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/transport"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// UnixServer listens on a local socket such as /dev/log and parses the
+// messages glibc syslog(3) writes there. Every message carries the
+// credentials of the sending process, SCM_CREDENTIALS for datagrams and
+// SO_PEERCRED for streams.
+type UnixServer struct {
+	path       string
+	network    string
+	handler    Handler
+	errHandler ErrorHandler
+	parse      ParseFunc
+	maxLen     int
+
+	mu       sync.Mutex
+	conn     *net.UnixConn
+	listener *net.UnixListener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewUnixServer returns a datagram server, the socket type of /dev/log.
+func NewUnixServer(path string, handler Handler) *UnixServer {
+	return newUnixServer(path, "unixgram", handler)
+}
+
+// NewUnixStreamServer returns a stream server, messages are NUL terminated
+// as glibc syslog(3) writes them on stream sockets.
+func NewUnixStreamServer(path string, handler Handler) *UnixServer {
+	return newUnixServer(path, "unix", handler)
+}
+
+func newUnixServer(path, network string, handler Handler) *UnixServer {
+	return &UnixServer{
+		path:    path,
+		network: network,
+		handler: handler,
+		parse:   psyslog.ParseLocal,
+		maxLen:  transport.MAXFRAMELEN,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *UnixServer) WithParseFunc(f ParseFunc) {
+	s.parse = f
+}
+
+// WithMaxPacketLen sets the datagram buffer size and the stream frame size
+// limit. It defaults to MAXFRAMELEN.
+func (s *UnixServer) WithMaxPacketLen(l int) {
+	s.maxLen = l
+}
+
+func (s *UnixServer) WithErrorHandler(h ErrorHandler) {
+	s.errHandler = h
+}
+
+// Listen binds the socket, a stale socket left at the path is removed. A
+// socket some process still listens on, such as the /dev/log of journald,
+// is left alone and Listen fails with EADDRINUSE.
+func (s *UnixServer) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil || s.listener != nil {
+		return ErrServerListening
+	}
+
+	if fi, err := os.Lstat(s.path); err == nil && fi.Mode()&os.ModeSocket != 0 && stale(s.path, s.network) {
+		os.Remove(s.path)
+	}
+
+	addr := &net.UnixAddr{Name: s.path, Net: s.network}
+
+	if s.network == "unix" {
+		listener, err := net.ListenUnix(s.network, addr)
+		if err != nil {
+			return err
+		}
+
+		s.listener = listener
+		return nil
+	}
+
+	conn, err := net.ListenUnixgram(s.network, addr)
+	if err != nil {
+		return err
+	}
+
+	if err := setPassCred(conn); err != nil {
+		conn.Close()
+		os.Remove(s.path)
+		return err
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// Serve reads messages until ctx is done, the socket file is removed
+// before it returns.
+func (s *UnixServer) Serve(ctx context.Context) error {
+	s.mu.Lock()
+	conn, listener := s.conn, s.listener
+	s.mu.Unlock()
+
+	var closer io.Closer
+
+	switch {
+	case conn != nil:
+		closer = conn
+	case listener != nil:
+		closer = listener
+	default:
+		return ErrServerNotListening
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			closer.Close()
+		case <-done:
+		}
+	}()
+
+	defer func() {
+		closer.Close()
+		os.Remove(s.path)
+
+		s.mu.Lock()
+		s.conn = nil
+		s.listener = nil
+		s.mu.Unlock()
+	}()
+
+	if conn != nil {
+		return s.serveDatagrams(ctx, conn)
+	}
+
+	return s.serveStreams(ctx, listener)
+}
+
+// ListenAndServe binds the socket and serves until ctx is done.
+func (s *UnixServer) ListenAndServe(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	return s.Serve(ctx)
+}
+
+func (s *UnixServer) serveDatagrams(ctx context.Context, conn *net.UnixConn) error {
+	buff := make([]byte, s.maxLen)
+	oob := make([]byte, syscall.CmsgSpace(syscall.SizeofUcred))
+
+	for {
+		n, oobn, _, addr, err := conn.ReadMsgUnix(buff, oob)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		raw := trimFrame(buff[:n])
+		res, err := s.parse(raw)
+
+		msg := &Message{
+			Result:      res,
+			Raw:         raw,
+			Err:         err,
+			Credentials: parseCredentials(oob[:oobn]),
+		}
+
+		if addr != nil {
+			msg.Addr = addr
+		}
+
+		s.handler(msg)
+	}
+}
+
+func (s *UnixServer) serveStreams(ctx context.Context, listener *net.UnixListener) error {
+	defer func() {
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+
+		s.wg.Wait()
+	}()
+
+	for {
+		conn, err := listener.AcceptUnix()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+
+				conn.Close()
+			}()
+
+			s.serveStream(conn)
+		}()
+	}
+}
+
+func (s *UnixServer) serveStream(conn *net.UnixConn) {
+	creds, err := peerCredentials(conn)
+	if err != nil {
+		s.reportError(conn.RemoteAddr(), err)
+	}
+
+	reader := transport.NewReader(conn, transport.FramingNonTransparent).
+		WithTrailer(0).
+		WithMaxFrameLen(s.maxLen)
+
+	for {
+		frame, err := reader.ReadFrame()
+
+		switch {
+		case err == nil:
+		case errors.Is(err, transport.ErrFrameTooLong):
+			s.reportError(conn.RemoteAddr(), err)
+			continue
+		case err == io.EOF || errors.Is(err, net.ErrClosed):
+			return
+		default:
+			s.reportError(conn.RemoteAddr(), err)
+			return
+		}
+
+		raw := trimFrame(frame)
+		res, err := s.parse(raw)
+
+		s.handler(&Message{
+			Result:      res,
+			Raw:         raw,
+			Addr:        conn.RemoteAddr(),
+			Err:         err,
+			Credentials: creds,
+		})
+	}
+}
+
+func (s *UnixServer) reportError(addr net.Addr, err error) {
+	if s.errHandler != nil {
+		s.errHandler(addr, err)
+	}
+}
+
+// stale reports whether nobody listens on the socket at path, connecting
+// to it is then refused.
+func stale(path, network string) bool {
+	conn, err := net.Dial(network, path)
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+
+	conn.Close()
+	return false
+}
+
+func setPassCred(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+
+	err = raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
+
+func peerCredentials(conn *net.UnixConn) (*Credentials, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ucred   *syscall.Ucred
+		sockErr error
+	)
+
+	err = raw.Control(func(fd uintptr) {
+		ucred, sockErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if sockErr != nil {
+		return nil, sockErr
+	}
+
+	return &Credentials{Pid: ucred.Pid, Uid: ucred.Uid, Gid: ucred.Gid}, nil
+}
+
+func parseCredentials(oob []byte) *Credentials {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil
+	}
+
+	for _, m := range msgs {
+		ucred, err := syscall.ParseUnixCredentials(&m)
+		if err == nil {
+			return &Credentials{Pid: ucred.Pid, Uid: ucred.Uid, Gid: ucred.Gid}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package server_test
+
+import (
+	"errors"
+	"github.com/deadspacewii/psyslog"
+	"github.com/deadspacewii/psyslog/client"
+	"github.com/deadspacewii/psyslog/server"
+	"github.com/deadspacewii/psyslog/transport"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// localMessage is what glibc syslog(3) writes, RFC 3164 without HOSTNAME.
+const localMessage = "<13>Oct 11 22:14:15 su[230]: 'su root' failed for lonvick"
+
+func TestUnixServer(t *testing.T) {
+	tests := []struct {
+		name      string
+		newServer func(path string, handler server.Handler) *server.UnixServer
+	}{
+		{"datagram", server.NewUnixServer},
+		{"stream", server.NewUnixStreamServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "log")
+			handler, errHandler, events := collect()
+
+			srv := tt.newServer(path, handler)
+			srv.WithErrorHandler(errHandler)
+			serve(t, srv)
+
+			// the writer falls back to a NUL terminated stream when the
+			// socket is not a datagram one
+			w, err := client.NewWriter("unix://" + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			for _, line := range []string{localMessage, rfc5424Message} {
+				if _, err := w.Write([]byte(line)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, want := range []psyslog.Format{psyslog.FormatRFC3164, psyslog.FormatRFC5424} {
+				e := nextEvent(t, events)
+				if e.err != nil || e.msg.Err != nil {
+					t.Fatalf("got %v, %v", e.err, e.msg.Err)
+				}
+
+				if e.msg.Result.Format != want {
+					t.Errorf("got %s, want %s", e.msg.Result.Format, want)
+				}
+
+				creds := e.msg.Credentials
+				if creds == nil {
+					t.Fatal("no credentials")
+				}
+
+				if int(creds.Pid) != os.Getpid() || int(creds.Uid) != os.Getuid() || int(creds.Gid) != os.Getgid() {
+					t.Errorf("got credentials %+v, want pid %d uid %d gid %d", *creds, os.Getpid(), os.Getuid(), os.Getgid())
+				}
+			}
+		})
+	}
+}
+
+func TestUnixStreamFrameTooLong(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	handler, errHandler, events := collect()
+
+	srv := server.NewUnixStreamServer(path, handler)
+	srv.WithErrorHandler(errHandler)
+	srv.WithMaxPacketLen(len(localMessage))
+	serve(t, srv)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream := localMessage + strings.Repeat("x", 32) + "\x00" + localMessage + "\x00"
+	if _, err := conn.Write([]byte(stream)); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := nextEvent(t, events); !errors.Is(e.err, transport.ErrFrameTooLong) {
+		t.Fatalf("got %v, want %v", e.err, transport.ErrFrameTooLong)
+	}
+
+	if e := nextEvent(t, events); e.err != nil || string(e.msg.Raw) != localMessage {
+		t.Fatalf("got %v, want %q", e.err, localMessage)
+	}
+}
+
+func TestUnixServerSocketFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+
+	// a stale socket left by a crashed server
+	stale, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.Close()
+
+	handler, _, _ := collect()
+	srv := server.NewUnixServer(path, handler)
+
+	// the server stops with the subtest, Listen replaced the stale socket
+	t.Run("serve", func(t *testing.T) {
+		serve(t, srv)
+	})
+
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left at %s: %v", path, err)
+	}
+}
+
+func TestUnixServerLiveSocket(t *testing.T) {
+	for _, network := range []string{"unixgram", "unix"} {
+		t.Run(network, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "log")
+
+			// another daemon owning the socket, as journald owns /dev/log
+			var live io.Closer
+			var err error
+
+			if network == "unix" {
+				live, err = net.ListenUnix(network, &net.UnixAddr{Name: path, Net: network})
+			} else {
+				live, err = net.ListenUnixgram(network, &net.UnixAddr{Name: path, Net: network})
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer live.Close()
+
+			handler, _, _ := collect()
+
+			for _, srv := range []*server.UnixServer{server.NewUnixServer(path, handler), server.NewUnixStreamServer(path, handler)} {
+				if err := srv.Listen(); !errors.Is(err, syscall.EADDRINUSE) {
+					t.Fatalf("got %v, want %v", err, syscall.EADDRINUSE)
+				}
+			}
+
+			if _, err := os.Lstat(path); err != nil {
+				t.Fatalf("live socket removed: %v", err)
+			}
+		})
+	}
+}
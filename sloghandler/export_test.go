@@ -0,0 +1,11 @@
+package sloghandler
+
+// SetHostname replaces os.Hostname until restore is called.
+func SetHostname(f func() (string, error)) (restore func()) {
+	saved := hostname
+	hostname = f
+
+	return func() {
+		hostname = saved
+	}
+}
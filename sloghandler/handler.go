@@ -0,0 +1,238 @@
+// Package sloghandler provides a log/slog Handler emitting RFC 5424
+// messages built with rfc5424.Builder.
+package sloghandler
+
+import (
+	"context"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DEFAULTSDID is the SD-ID holding the record attributes, 32473 is the
+	// enterprise number reserved for documentation.
+	DEFAULTSDID = "slog@32473"
+	// DEFAULTFACILITY is user-level messages.
+	DEFAULTFACILITY = common.FacilityUser
+)
+
+// Options configure a Handler, the zero value is usable.
+type Options struct {
+	// Level is the minimum level handled, slog.LevelInfo when nil.
+	Level slog.Leveler
+	// Facility of every message, DEFAULTFACILITY when zero. Like glibc
+	// syslog(3) there is no way to log as the kernel facility.
+	Facility common.Facility
+	// Hostname defaults to os.Hostname, NILVALUE when it fails.
+	Hostname string
+	// AppName defaults to the program name.
+	AppName string
+	// ProcId defaults to the process id.
+	ProcId string
+	// MsgId is NILVALUE when empty.
+	MsgId string
+	// SDID names the SD element carrying the attributes, DEFAULTSDID when
+	// empty. Attributes in groups are named group.attr.
+	SDID string
+}
+
+// Handler writes one RFC 5424 message per record to w, a client.Writer
+// sends them to a collector. Messages written to other writers are LF
+// terminated.
+type Handler struct {
+	opts   Options
+	w      io.Writer
+	mu     *sync.Mutex
+	params []rfc5424.SDParam
+	prefix string
+}
+
+func NewHandler(w io.Writer, opts *Options) *Handler {
+	h := &Handler{
+		w:  w,
+		mu: &sync.Mutex{},
+	}
+
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+
+	if h.opts.Facility == 0 {
+		h.opts.Facility = DEFAULTFACILITY
+	}
+
+	if h.opts.Hostname == "" {
+		h.opts.Hostname = defaultHostname()
+	}
+
+	if h.opts.AppName == "" {
+		h.opts.AppName = filepath.Base(os.Args[0])
+	}
+
+	if h.opts.ProcId == "" {
+		h.opts.ProcId = strconv.Itoa(os.Getpid())
+	}
+
+	if h.opts.SDID == "" {
+		h.opts.SDID = DEFAULTSDID
+	}
+
+	return h
+}
+
+// hostname is os.Hostname, the tests make it fail.
+var hostname = os.Hostname
+
+// defaultHostname is the name of the host, NILVALUE rather than an empty
+// HOSTNAME when it is unknown.
+func defaultHostname() string {
+	name, err := hostname()
+	if err != nil || name == "" {
+		return string(rfc5424.NILVALUE)
+	}
+
+	return name
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	params := append([]rfc5424.SDParam(nil), h.params...)
+
+	r.Attrs(func(attr slog.Attr) bool {
+		params = appendAttr(params, h.prefix, attr)
+		return true
+	})
+
+	builder := rfc5424.NewBuilder().
+		SetFacilitySeverity(h.opts.Facility, Severity(r.Level)).
+		SetVersion(1).
+		SetHostName(h.opts.Hostname).
+		SetAppName(h.opts.AppName).
+		SetProcId(h.opts.ProcId).
+		SetMsgId(h.opts.MsgId).
+		SetMessage(r.Message)
+
+	if !r.Time.IsZero() {
+		builder.SetTimestamp(r.Time.Format(rfc5424.TIMESTAMPFORMAT))
+	}
+
+	if len(params) > 0 {
+		builder.AddSDElement(h.opts.SDID, params...)
+	}
+
+	if err := builder.Build(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := io.WriteString(h.w, builder.String()+"\n")
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.params = append([]rfc5424.SDParam(nil), h.params...)
+
+	for _, attr := range attrs {
+		h2.params = appendAttr(h2.params, h.prefix, attr)
+	}
+
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Severity maps a slog level to the closest syslog severity.
+func Severity(level slog.Level) common.Severity {
+	switch {
+	case level < slog.LevelInfo:
+		return common.SeverityDebug
+	case level < slog.LevelWarn:
+		return common.SeverityInfo
+	case level < slog.LevelError:
+		return common.SeverityWarning
+	case level < slog.LevelError+4:
+		return common.SeverityErr
+	}
+
+	return common.SeverityCrit
+}
+
+func appendAttr(params []rfc5424.SDParam, prefix string, attr slog.Attr) []rfc5424.SDParam {
+	value := attr.Value.Resolve()
+
+	if attr.Equal(slog.Attr{}) {
+		return params
+	}
+
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+
+		for _, a := range value.Group() {
+			params = appendAttr(params, prefix, a)
+		}
+
+		return params
+	}
+
+	var s string
+	if value.Kind() == slog.KindTime {
+		s = value.Time().Format(time.RFC3339Nano)
+	} else {
+		s = value.String()
+	}
+
+	return append(params, rfc5424.SDParam{
+		Name:  paramName(prefix + attr.Key),
+		Value: s,
+	})
+}
+
+// paramName fits name into PARAM-NAME, 1*32 PRINTUSASCII except '=', SP,
+// ']' and '"'.
+func paramName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	if len(name) > 32 {
+		name = name[:32]
+	}
+
+	if name == "" {
+		name = "_"
+	}
+
+	return name
+}
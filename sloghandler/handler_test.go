@@ -0,0 +1,157 @@
+package sloghandler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/deadspacewii/psyslog/common"
+	"github.com/deadspacewii/psyslog/rfc5424"
+	"github.com/deadspacewii/psyslog/sloghandler"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+var recordTime = time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+
+func newHandler(buff *bytes.Buffer, level slog.Leveler) *sloghandler.Handler {
+	return sloghandler.NewHandler(buff, &sloghandler.Options{
+		Level:    level,
+		Facility: common.FacilityLocal4,
+		Hostname: "mymachine.example.com",
+		AppName:  "evntslog",
+		ProcId:   "42",
+		MsgId:    "ID47",
+	})
+}
+
+func handle(t *testing.T, h slog.Handler, level slog.Level, msg string, attrs ...slog.Attr) {
+	t.Helper()
+
+	r := slog.NewRecord(recordTime, level, msg, 0)
+	r.AddAttrs(attrs...)
+
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandlerFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(t *testing.T, h *sloghandler.Handler)
+		want   string
+	}{
+		{
+			name: "no attributes",
+			handle: func(t *testing.T, h *sloghandler.Handler) {
+				handle(t, h, slog.LevelInfo, "An application event")
+			},
+			want: `<166>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 - An application event`,
+		},
+		{
+			name: "attributes",
+			handle: func(t *testing.T, h *sloghandler.Handler) {
+				handle(t, h, slog.LevelWarn, "disk full", slog.String("dev", "sda1"), slog.Int("used", 99))
+			},
+			want: `<164>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 [slog@32473 dev="sda1" used="99"] disk full`,
+		},
+		{
+			name: "groups",
+			handle: func(t *testing.T, h *sloghandler.Handler) {
+				h2 := h.WithAttrs([]slog.Attr{slog.String("id", "7")}).WithGroup("req")
+				handle(t, h2, slog.LevelError, "failed", slog.Group("user", slog.String("name", "lonvick")))
+			},
+			want: `<163>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 [slog@32473 id="7" req.user.name="lonvick"] failed`,
+		},
+		{
+			name: "escaped value and param name",
+			handle: func(t *testing.T, h *sloghandler.Handler) {
+				handle(t, h, slog.LevelInfo, "x", slog.String(`a b="c"`, `say "hi"]`))
+			},
+			want: `<166>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 [slog@32473 a_b__c_="say \"hi\"\]"] x`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+
+			tt.handle(t, newHandler(&buff, nil))
+
+			got := buff.String()
+			if got != tt.want+"\n" {
+				t.Fatalf("got  %q\nwant %q", got, tt.want+"\n")
+			}
+
+			if _, err := rfc5424.NewParser[any]().ParseBytes([]byte(strings.TrimSuffix(got, "\n"))); err != nil {
+				t.Errorf("output does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandlerLevel(t *testing.T) {
+	var buff bytes.Buffer
+
+	logger := slog.New(newHandler(&buff, slog.LevelWarn))
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	if got := strings.Count(buff.String(), "\n"); got != 1 || !strings.HasSuffix(buff.String(), " kept\n") {
+		t.Errorf("got %q, want the warning only", buff.String())
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  common.Severity
+	}{
+		{slog.LevelDebug, common.SeverityDebug},
+		{slog.LevelInfo, common.SeverityInfo},
+		{slog.LevelInfo + 2, common.SeverityInfo},
+		{slog.LevelWarn, common.SeverityWarning},
+		{slog.LevelError, common.SeverityErr},
+		{slog.LevelError + 4, common.SeverityCrit},
+	}
+
+	for _, tt := range tests {
+		if got := sloghandler.Severity(tt.level); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname func() (string, error)
+		want     string
+	}{
+		{"host name", func() (string, error) { return "mymachine", nil }, "mymachine"},
+		{"failure", func() (string, error) { return "", errors.New("no host name") }, "-"},
+		{"empty host name", func() (string, error) { return "", nil }, "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer sloghandler.SetHostname(tt.hostname)()
+
+			var buff bytes.Buffer
+
+			h := sloghandler.NewHandler(&buff, &sloghandler.Options{AppName: "evntslog", ProcId: "42"})
+			handle(t, h, slog.LevelInfo, "x")
+
+			res, err := rfc5424.NewParser[any]().ParseBytes(bytes.TrimSuffix(buff.Bytes(), []byte("\n")))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Hostname != tt.want {
+				t.Errorf("got HOSTNAME %q, want %q", res.Hostname, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Package transport holds the stream framing shared by the syslog servers
+// and clients.
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Framing tells how messages are delimited on a stream.
+// https://tools.ietf.org/html/rfc6587#section-3.4
+type Framing int
+
+const (
+	// FramingAuto picks octet counting when the first frame starts with a
+	// digit and non-transparent framing otherwise, a message always starts
+	// with '<'. The framing picked holds for the rest of the stream.
+	FramingAuto Framing = iota
+	// FramingOctetCounting is MSG-LEN SP SYSLOG-MSG.
+	FramingOctetCounting
+	// FramingNonTransparent is SYSLOG-MSG TRAILER.
+	FramingNonTransparent
+)
+
+const (
+	TRAILER = '\n'
+
+	// MAXFRAMELEN is the default frame size limit, the same as the
+	// MAXPACKETLEN of the parsers.
+	MAXFRAMELEN = 5120
+
+	// maxCountDigits bounds MSG-LEN, no sane frame needs more digits.
+	maxCountDigits = 10
+)
+
+var (
+	// ErrFrameTooLong is returned for a frame over the limit, the frame is
+	// skipped and the reader stays usable.
+	ErrFrameTooLong = errors.New("Frame too long")
+	// ErrInvalidOctetCount means the stream lost its framing, it is not
+	// possible to read further frames.
+	ErrInvalidOctetCount = errors.New("Invalid octet count")
+)
+
+func (f Framing) String() string {
+	switch f {
+	case FramingOctetCounting:
+		return "octet-counting"
+	case FramingNonTransparent:
+		return "non-transparent"
+	}
+
+	return "auto"
+}
+
+// Reader reads frames from a stream, partial reads are buffered until a
+// frame is complete.
+type Reader struct {
+	r       *bufio.Reader
+	framing Framing
+	trailer byte
+	maxLen  int
+	lines   int
+	line    int
+}
+
+func NewReader(r io.Reader, framing Framing) *Reader {
+	return &Reader{
+		r:       bufio.NewReader(r),
+		framing: framing,
+		trailer: TRAILER,
+		maxLen:  MAXFRAMELEN,
+	}
+}
+
+// WithTrailer sets the byte ending a non-transparent frame, LF by default.
+// Some senders use NUL instead.
+func (fr *Reader) WithTrailer(trailer byte) *Reader {
+	fr.trailer = trailer
+	return fr
+}
+
+func (fr *Reader) WithMaxFrameLen(l int) *Reader {
+	fr.maxLen = l
+	return fr
+}
+
+// Line returns the 1-based line the last frame read started on, LFs inside
+// frames and between them are counted whatever the framing.
+func (fr *Reader) Line() int {
+	return fr.line
+}
+
+// ReadFrame returns the next frame without its framing. The returned slice
+// is only valid until the next call. io.EOF is returned once the stream
+// ends between frames.
+func (fr *Reader) ReadFrame() ([]byte, error) {
+	c, err := fr.skipSeparators()
+	if err != nil {
+		return nil, err
+	}
+
+	fr.line = fr.lines + 1
+
+	// a sender does not switch framing, detecting it on every frame would
+	// read a line starting with digits as an octet count
+	if fr.framing == FramingAuto {
+		fr.framing = FramingNonTransparent
+
+		if c >= '0' && c <= '9' {
+			fr.framing = FramingOctetCounting
+		}
+	}
+
+	if fr.framing == FramingOctetCounting {
+		return fr.readOctetCounted()
+	}
+
+	return fr.readNonTransparent()
+}
+
+// skipSeparators drops the line endings left between frames and peeks the
+// first byte of the next one.
+func (fr *Reader) skipSeparators() (byte, error) {
+	for {
+		b, err := fr.r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+
+		c := b[0]
+		if c != '\n' && c != '\r' && c != fr.trailer {
+			return c, nil
+		}
+
+		if c == '\n' {
+			fr.lines++
+		}
+
+		fr.r.Discard(1)
+	}
+}
+
+// MSG-LEN = NONZERO-DIGIT *DIGIT
+func (fr *Reader) readOctetCounted() ([]byte, error) {
+	n := 0
+
+	for digits := 0; ; digits++ {
+		c, err := fr.r.ReadByte()
+		if err != nil {
+			return nil, unexpected(err)
+		}
+
+		if c == ' ' && digits > 0 {
+			break
+		}
+
+		if c < '0' || c > '9' || (digits == 0 && c == '0') || digits >= maxCountDigits {
+			return nil, ErrInvalidOctetCount
+		}
+
+		n = n*10 + int(c-'0')
+	}
+
+	if n > fr.maxLen {
+		if err := fr.discard(n); err != nil {
+			return nil, unexpected(err)
+		}
+
+		return nil, ErrFrameTooLong
+	}
+
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(fr.r, frame); err != nil {
+		return nil, unexpected(err)
+	}
+
+	fr.lines += bytes.Count(frame, []byte{'\n'})
+
+	return frame, nil
+}
+
+func (fr *Reader) readNonTransparent() ([]byte, error) {
+	var frame []byte
+
+	tooLong := false
+
+	for {
+		chunk, err := fr.r.ReadSlice(fr.trailer)
+		fr.lines += bytes.Count(chunk, []byte{'\n'})
+
+		if !tooLong {
+			frame = append(frame, chunk...)
+
+			if len(frame) > fr.maxLen+1 {
+				tooLong = true
+				frame = nil
+			}
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case tooLong && (err == nil || err == io.EOF):
+			return nil, ErrFrameTooLong
+		case err == io.EOF && len(frame) > 0:
+			// the last frame of a stream may lack its trailer
+			return frame, nil
+		case err != nil:
+			return nil, err
+		}
+
+		return frame[:len(frame)-1], nil
+	}
+}
+
+// discard skips n bytes counting the LFs among them.
+func (fr *Reader) discard(n int) error {
+	for n > 0 {
+		size := fr.r.Size()
+		if size > n {
+			size = n
+		}
+
+		b, err := fr.r.Peek(size)
+		if len(b) == 0 && err != nil {
+			return err
+		}
+
+		fr.lines += bytes.Count(b, []byte{'\n'})
+		fr.r.Discard(len(b))
+		n -= len(b)
+	}
+
+	return nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// AppendFrame appends msg framed with framing to dst, FramingAuto is
+// written as octet counting.
+func AppendFrame(dst []byte, framing Framing, msg []byte) []byte {
+	if framing == FramingNonTransparent {
+		dst = append(dst, msg...)
+		return append(dst, TRAILER)
+	}
+
+	dst = strconv.AppendInt(dst, int64(len(msg)), 10)
+	dst = append(dst, ' ')
+	return append(dst, msg...)
+}
+
+// WriteFrame writes msg framed with framing in a single Write.
+func WriteFrame(w io.Writer, framing Framing, msg []byte) error {
+	_, err := w.Write(AppendFrame(nil, framing, msg))
+	return err
+}
@@ -0,0 +1,177 @@
+package transport_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/deadspacewii/psyslog/transport"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readAll returns the frames read from fr until the stream ends, a frame
+// over the limit shows as "!" and the error ending the stream as "err: ".
+func readAll(fr *transport.Reader) []string {
+	var frames []string
+
+	for {
+		frame, err := fr.ReadFrame()
+
+		switch {
+		case errors.Is(err, transport.ErrFrameTooLong):
+			frames = append(frames, "!")
+		case err == io.EOF:
+			return frames
+		case err != nil:
+			return append(frames, "err: "+err.Error())
+		default:
+			frames = append(frames, string(frame))
+		}
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing transport.Framing
+		stream  string
+		want    []string
+	}{
+		{
+			name:    "octet counting",
+			framing: transport.FramingOctetCounting,
+			stream:  "5 <1>ab7 <1>a\ncd",
+			want:    []string{"<1>ab", "<1>a\ncd"},
+		},
+		{
+			name:    "non-transparent",
+			framing: transport.FramingNonTransparent,
+			stream:  "<1>ab\n<1>cd\r\n\n<1>ef",
+			want:    []string{"<1>ab", "<1>cd\r", "<1>ef"},
+		},
+		{
+			name:    "auto octet counting",
+			framing: transport.FramingAuto,
+			stream:  "5 <1>ab\n5 <1>cd",
+			want:    []string{"<1>ab", "<1>cd"},
+		},
+		{
+			name:    "auto non-transparent",
+			framing: transport.FramingAuto,
+			stream:  "<1>ab\n1 <1>c\n",
+			want:    []string{"<1>ab", "1 <1>c"},
+		},
+		{
+			name:    "octet counting too long",
+			framing: transport.FramingOctetCounting,
+			stream:  "5 <1>ab11 <1>abcdefgh5 <1>cd",
+			want:    []string{"<1>ab", "!", "<1>cd"},
+		},
+		{
+			name:    "non-transparent too long",
+			framing: transport.FramingNonTransparent,
+			stream:  "<1>ab\n<1>abcdefgh\n<1>cd\n",
+			want:    []string{"<1>ab", "!", "<1>cd"},
+		},
+		{
+			name:    "too long at the end of the stream",
+			framing: transport.FramingNonTransparent,
+			stream:  "<1>ab\n<1>abcdefgh",
+			want:    []string{"<1>ab", "!"},
+		},
+		{
+			name:    "invalid octet count",
+			framing: transport.FramingOctetCounting,
+			stream:  "5 <1>ab05 <1>cd",
+			want:    []string{"<1>ab", "err: " + transport.ErrInvalidOctetCount.Error()},
+		},
+		{
+			name:    "truncated octet counted frame",
+			framing: transport.FramingOctetCounting,
+			stream:  "9 <1>ab",
+			want:    []string{"err: " + io.ErrUnexpectedEOF.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := transport.NewReader(iotest.OneByteReader(strings.NewReader(tt.stream)), tt.framing).
+				WithMaxFrameLen(8)
+
+			got := readAll(fr)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFrameTrailer(t *testing.T) {
+	fr := transport.NewReader(strings.NewReader("<1>ab\x00<1>c\nd\x00"), transport.FramingNonTransparent).
+		WithTrailer(0)
+
+	got := readAll(fr)
+	if want := []string{"<1>ab", "<1>c\nd"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// a line starting with digits is not taken for an octet count once the
+// stream is known to be non-transparent
+func TestReadFrameAutoKeepsFraming(t *testing.T) {
+	stream := "<34>Oct 11 22:14:15 mymachine beer: first\n12 bottles of beer on the wall, long line\n"
+	fr := transport.NewReader(strings.NewReader(stream), transport.FramingAuto)
+
+	got := readAll(fr)
+	want := []string{"<34>Oct 11 22:14:15 mymachine beer: first", "12 bottles of beer on the wall, long line"}
+
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReadFrameLine(t *testing.T) {
+	tests := []struct {
+		stream string
+		lines  []int
+	}{
+		{"<1>a\n\n<1>b\n<1>multi\n<1>c", []int{1, 3, 4, 5}},
+		{"11 <1>multi\nli\n5 <1>ab5 <1>c\n", []int{1, 3, 3}},
+	}
+
+	for _, tt := range tests {
+		fr := transport.NewReader(strings.NewReader(tt.stream), transport.FramingAuto)
+
+		for _, want := range tt.lines {
+			if _, err := fr.ReadFrame(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fr.Line(); got != want {
+				t.Errorf("%q: got line %d, want %d", tt.stream, got, want)
+			}
+		}
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	messages := []string{"<1>ab", "<1>a b", "<1>c"}
+
+	for _, framing := range []transport.Framing{transport.FramingOctetCounting, transport.FramingNonTransparent} {
+		t.Run(framing.String(), func(t *testing.T) {
+			var buff bytes.Buffer
+
+			for _, msg := range messages {
+				if err := transport.WriteFrame(&buff, framing, []byte(msg)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := readAll(transport.NewReader(&buff, transport.FramingAuto))
+			if strings.Join(got, "|") != strings.Join(messages, "|") {
+				t.Errorf("got %q, want %q", got, messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package transport
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrNoPeerCertificate = errors.New("No peer certificate")
+	ErrFingerprintFormat = errors.New("Fingerprint format unknown")
+	ErrFingerprintPinned = errors.New("Peer certificate fingerprint is not pinned")
+)
+
+// Fingerprint returns the SHA-256 fingerprint of cert in the notation of
+// https://tools.ietf.org/html/rfc5425#section-4.2.2, e.g. "SHA-256:0A:1B:...".
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return "SHA-256:" + formatHex(sum[:])
+}
+
+// PinServer returns a copy of the server config which requires every client
+// to present a certificate whose leaf matches one of fingerprints, an
+// anonymous client is refused. Pinning replaces chain validation as
+// https://tools.ietf.org/html/rfc5425#section-5.2 allows, so self-signed
+// certificates work. SHA-1 and SHA-256 fingerprints are accepted, case and
+// colons are ignored.
+func PinServer(config *tls.Config, fingerprints ...string) (*tls.Config, error) {
+	cfg, err := pin(config, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ClientAuth = tls.RequireAnyClientCert
+
+	return cfg, nil
+}
+
+// PinClient returns a copy of the client config which accepts a server only
+// if its leaf certificate matches one of fingerprints, see PinServer.
+func PinClient(config *tls.Config, fingerprints ...string) (*tls.Config, error) {
+	cfg, err := pin(config, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.InsecureSkipVerify = true
+
+	return cfg, nil
+}
+
+func pin(config *tls.Config, fingerprints []string) (*tls.Config, error) {
+	if len(fingerprints) == 0 {
+		return nil, ErrFingerprintFormat
+	}
+
+	pins := make(map[string]struct{}, len(fingerprints))
+
+	for _, fp := range fingerprints {
+		norm, err := normalizeFingerprint(fp)
+		if err != nil {
+			return nil, err
+		}
+
+		pins[norm] = struct{}{}
+	}
+
+	cfg := config.Clone()
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+
+	cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return ErrNoPeerCertificate
+		}
+
+		sha1Sum := sha1.Sum(rawCerts[0])
+		sha256Sum := sha256.Sum256(rawCerts[0])
+
+		for _, candidate := range []string{
+			"SHA-1:" + formatHex(sha1Sum[:]),
+			"SHA-256:" + formatHex(sha256Sum[:]),
+		} {
+			if _, ok := pins[candidate]; ok {
+				return nil
+			}
+		}
+
+		return ErrFingerprintPinned
+	}
+
+	return cfg, nil
+}
+
+// PeerCertificate returns the leaf certificate presented by the peer, nil
+// when there is none.
+func PeerCertificate(state tls.ConnectionState) *x509.Certificate {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	return state.PeerCertificates[0]
+}
+
+func normalizeFingerprint(fp string) (string, error) {
+	name, digest, found := strings.Cut(fp, ":")
+	if !found {
+		return "", ErrFingerprintFormat
+	}
+
+	raw, err := hex.DecodeString(strings.ReplaceAll(digest, ":", ""))
+	if err != nil {
+		return "", ErrFingerprintFormat
+	}
+
+	switch strings.ToUpper(strings.ReplaceAll(name, "-", "")) {
+	case "SHA1":
+		if len(raw) == sha1.Size {
+			return "SHA-1:" + formatHex(raw), nil
+		}
+	case "SHA256":
+		if len(raw) == sha256.Size {
+			return "SHA-256:" + formatHex(raw), nil
+		}
+	}
+
+	return "", ErrFingerprintFormat
+}
+
+func formatHex(b []byte) string {
+	var sb strings.Builder
+
+	for i, c := range b {
+		if i > 0 {
+			sb.WriteByte(':')
+		}
+
+		sb.WriteString(strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+
+	return sb.String()
+}